@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+// Minimum contrast ratios required by WCAG 2 level AA.
+const (
+	ContrastAA      = 4.5
+	ContrastAALarge = 3.0
+)
+
+// ContrastCheck is the contrast between one of a work's colors and the text color of a theme.
+type ContrastCheck struct {
+	// Which of the work's colors was checked: primary, secondary, tertiary or pageBackground.
+	Color         string  `json:"color"`
+	Value         string  `json:"value"`
+	Theme         string  `json:"theme"`
+	TextColor     string  `json:"textColor"`
+	Ratio         float64 `json:"ratio"`
+	PassesAA      bool    `json:"passesAA"`
+	PassesAALarge bool    `json:"passesAALarge"`
+}
+
+// AccessibilityReport lists the contrast checks of a work's colors.
+type AccessibilityReport struct {
+	WorkID   string          `json:"workID"`
+	Checks   []ContrastCheck `json:"checks"`
+	PassesAA bool            `json:"passesAA"`
+	// Colors that could not be parsed, mapped to the parsing error.
+	Invalid map[string]string `json:"invalid"`
+}
+
+// CheckWorkAccessibility computes the contrast ratios between the work's colors and the text colors of every theme.
+// Unset colors are not checked.
+func CheckWorkAccessibility(work ortfodb.Work) AccessibilityReport {
+	report := AccessibilityReport{
+		WorkID:   work.ID,
+		Checks:   make([]ContrastCheck, 0),
+		PassesAA: true,
+		Invalid:  make(map[string]string),
+	}
+	colors := []struct{ name, value string }{
+		{"primary", work.Metadata.Colors.Primary},
+		{"secondary", work.Metadata.Colors.Secondary},
+		{"tertiary", work.Metadata.Colors.Tertiary},
+		{"pageBackground", work.Metadata.PageBackground},
+	}
+
+	for _, color := range colors {
+		if color.value == "" {
+			continue
+		}
+		for _, theme := range ThemeNames {
+			ratio, err := ContrastRatio(ThemeTextColors[theme], color.value)
+			if err != nil {
+				report.Invalid[color.name] = err.Error()
+				break
+			}
+			check := ContrastCheck{
+				Color:         color.name,
+				Value:         color.value,
+				Theme:         theme,
+				TextColor:     ThemeTextColors[theme],
+				Ratio:         ratio,
+				PassesAA:      ratio >= ContrastAA,
+				PassesAALarge: ratio >= ContrastAALarge,
+			}
+			report.PassesAA = report.PassesAA && check.PassesAA
+			report.Checks = append(report.Checks, check)
+		}
+	}
+	return report
+}
+
+// CheckAccessibility checks the colors of the work with ID workID.
+func (settings *Settings) CheckAccessibility(workID string) (AccessibilityReport, error) {
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return AccessibilityReport{}, fmt.Errorf("while loading database: %w", err)
+	}
+	work, ok := db[workID]
+	if !ok {
+		return AccessibilityReport{}, fmt.Errorf("no work with ID %q in the database", workID)
+	}
+	return CheckWorkAccessibility(work), nil
+}
+
+// AccessibilityFailures checks the colors of every work of the portfolio, and returns the reports of works that fail WCAG AA.
+func (settings *Settings) AccessibilityFailures() ([]AccessibilityReport, error) {
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("while loading database: %w", err)
+	}
+
+	failures := make([]AccessibilityReport, 0)
+	for _, work := range db {
+		report := CheckWorkAccessibility(work)
+		if !report.PassesAA || len(report.Invalid) > 0 {
+			failures = append(failures, report)
+		}
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].WorkID < failures[j].WorkID
+	})
+	return failures, nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+// MediaAnalysisProgress describes where a batch media analysis is at, so that the UI can poll it.
+type MediaAnalysisProgress struct {
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	WorkID  string `json:"workID"`
+	File    string `json:"file"`
+	Running bool   `json:"running"`
+}
+
+// MediaAnalysisResult is the outcome of analyzing a single media file of a work.
+type MediaAnalysisResult struct {
+	WorkID  string `json:"workID"`
+	Source  string `json:"source"`
+	Skipped bool   `json:"skipped"`
+	Error   string `json:"error"`
+	// Optimization is the outcome of producing the media's web-optimized variant, when media optimization is enabled.
+	Optimization      MediaOptimization `json:"optimization"`
+	OptimizationError string            `json:"optimizationError"`
+}
+
+// mediaHashes maps absolute media file paths to the hash of their content at the time
+// their thumbnails were last built.
+type mediaHashes map[string]mediaHashEntry
+
+type mediaHashEntry struct {
+	Hash              string `json:"hash"`
+	ThumbnailsBuiltAt string `json:"thumbnailsBuiltAt"`
+}
+
+type mediaAnalysisJob struct {
+	workID string
+	media  ortfodb.Media
+}
+
+var mediaAnalysisProgress MediaAnalysisProgress
+var mediaAnalysisProgressLock sync.Mutex
+
+func setMediaAnalysisProgress(update func(progress *MediaAnalysisProgress)) {
+	mediaAnalysisProgressLock.Lock()
+	defer mediaAnalysisProgressLock.Unlock()
+	update(&mediaAnalysisProgress)
+}
+
+// MediaAnalysisProgressState returns a snapshot of the current batch media analysis' progress.
+func MediaAnalysisProgressState() MediaAnalysisProgress {
+	mediaAnalysisProgressLock.Lock()
+	defer mediaAnalysisProgressLock.Unlock()
+	return mediaAnalysisProgress
+}
+
+func loadMediaHashes() mediaHashes {
+	hashes := make(mediaHashes)
+	raw, err := os.ReadFile(ConfigurationDirectory("portfolio-database", "media-hashes.json"))
+	if err != nil {
+		return hashes
+	}
+	err = json.Unmarshal(raw, &hashes)
+	if err != nil {
+		// This file is only a cache, losing it just means re-analyzing everything once.
+		return make(mediaHashes)
+	}
+	return hashes
+}
+
+func (hashes mediaHashes) save() error {
+	raw, err := json.Marshal(hashes)
+	if err != nil {
+		return fmt.Errorf("while converting media hashes to JSON: %w", err)
+	}
+	return os.WriteFile(ConfigurationDirectory("portfolio-database", "media-hashes.json"), raw, 0644)
+}
+
+// hashFile returns the hash of the file's contents, encoded the same way ortfodb encodes description hashes.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// AnalyzeAllMedia analyzes every media of the works with the given IDs (or of all works if workIDs is empty)
+// using a bounded pool of workers, and writes the results back into the database once everything is analyzed.
+// Media files whose content did not change since their thumbnails were built are skipped, and duplicate work IDs are ignored.
+func (settings *Settings) AnalyzeAllMedia(workIDs []string) ([]MediaAnalysisResult, error) {
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("while loading database: %w", err)
+	}
+
+	if len(workIDs) == 0 {
+		for id := range db {
+			workIDs = append(workIDs, id)
+		}
+	}
+
+	hashes := loadMediaHashes()
+	results := make([]MediaAnalysisResult, 0)
+	jobs := make([]mediaAnalysisJob, 0)
+	currentHashes := make(map[string]string)
+	// A work listed twice would have its media analyzed twice at the same time.
+	seenWorks := make(map[string]bool)
+	for _, workID := range workIDs {
+		if seenWorks[workID] {
+			continue
+		}
+		seenWorks[workID] = true
+		work, ok := db[workID]
+		if !ok {
+			results = append(results, MediaAnalysisResult{WorkID: workID, Error: fmt.Sprintf("no work with ID %q in the database", workID)})
+			continue
+		}
+		queued := make(map[ortfodb.FilePathInsidePortfolioFolder]bool)
+		for _, content := range work.Content {
+			for _, block := range content.Blocks {
+				if !block.Type.IsMedia() || queued[block.RelativeSource] {
+					continue
+				}
+				queued[block.RelativeSource] = true
+				media := block.AsMedia()
+				absolutePath := media.RelativeSource.Absolute(ctx, workID)
+				hash, err := hashFile(absolutePath)
+				if err == nil {
+					currentHashes[absolutePath] = hash
+					if cached, ok := hashes[absolutePath]; ok && media.ThumbnailsBuiltAt != "" && cached.Hash == hash && cached.ThumbnailsBuiltAt == media.ThumbnailsBuiltAt {
+						results = append(results, MediaAnalysisResult{WorkID: workID, Source: string(media.RelativeSource), Skipped: true})
+						continue
+					}
+				}
+				jobs = append(jobs, mediaAnalysisJob{workID: workID, media: media})
+			}
+		}
+	}
+
+	setMediaAnalysisProgress(func(progress *MediaAnalysisProgress) {
+		*progress = MediaAnalysisProgress{Total: len(jobs), Running: true}
+	})
+	defer setMediaAnalysisProgress(func(progress *MediaAnalysisProgress) {
+		progress.Running = false
+	})
+
+	workersCount := ctx.Flags.WorkersCount
+	if workersCount <= 0 {
+		workersCount = runtime.NumCPU()
+	}
+
+	type analyzed struct {
+		job               mediaAnalysisJob
+		media             ortfodb.Media
+		err               error
+		optimization      MediaOptimization
+		optimizationError error
+	}
+
+	jobsChannel := make(chan mediaAnalysisJob)
+	analyzedChannel := make(chan analyzed)
+	var workers sync.WaitGroup
+	for i := 0; i < workersCount; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobsChannel {
+				_, media, _, err := ctx.AnalyzeMediaFile(job.workID, job.media)
+				result := analyzed{job: job, media: media, err: err}
+				if err == nil && settings.MediaOptimization.Enabled {
+					result.optimization, result.optimizationError = settings.OptimizeMedia(job.workID, media)
+				}
+				analyzedChannel <- result
+			}
+		}()
+	}
+	go func() {
+		for _, job := range jobs {
+			jobsChannel <- job
+		}
+		close(jobsChannel)
+		workers.Wait()
+		close(analyzedChannel)
+	}()
+
+	analyzedMedia := make(map[string]map[ortfodb.FilePathInsidePortfolioFolder]ortfodb.Media)
+	for result := range analyzedChannel {
+		setMediaAnalysisProgress(func(progress *MediaAnalysisProgress) {
+			progress.Done++
+			progress.WorkID = result.job.workID
+			progress.File = string(result.job.media.RelativeSource)
+		})
+		if result.err != nil {
+			results = append(results, MediaAnalysisResult{WorkID: result.job.workID, Source: string(result.job.media.RelativeSource), Error: result.err.Error()})
+			continue
+		}
+		if _, ok := analyzedMedia[result.job.workID]; !ok {
+			analyzedMedia[result.job.workID] = make(map[ortfodb.FilePathInsidePortfolioFolder]ortfodb.Media)
+		}
+		analyzedMedia[result.job.workID][result.job.media.RelativeSource] = result.media
+		analysisResult := MediaAnalysisResult{WorkID: result.job.workID, Source: string(result.job.media.RelativeSource), Optimization: result.optimization}
+		if result.optimizationError != nil {
+			analysisResult.OptimizationError = result.optimizationError.Error()
+		}
+		results = append(results, analysisResult)
+	}
+
+	for workID, mediae := range analyzedMedia {
+		for lang, content := range db[workID].Content {
+			for i, block := range content.Blocks {
+				media, ok := mediae[block.RelativeSource]
+				if !block.Type.IsMedia() || !ok {
+					continue
+				}
+				// Analysis does not generate thumbnails, keep the ones we already have.
+				media.Thumbnails = block.Thumbnails
+				media.ThumbnailsBuiltAt = block.ThumbnailsBuiltAt
+				db[workID].Content[lang].Blocks[i].Media = media
+				absolutePath := media.RelativeSource.Absolute(ctx, workID)
+				if hash, ok := currentHashes[absolutePath]; ok && media.ThumbnailsBuiltAt != "" {
+					hashes[absolutePath] = mediaHashEntry{Hash: hash, ThumbnailsBuiltAt: media.ThumbnailsBuiltAt}
+				}
+			}
+		}
+	}
+
+	ctx.WriteDatabase(db, ctx.Flags, ctx.OutputDatabaseFile, db.Partial())
+	err = hashes.save()
+	if err != nil {
+		return results, fmt.Errorf("while saving media hashes: %w", err)
+	}
+	return results, nil
+}
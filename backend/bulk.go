@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+// MetadataPatch describes changes to apply to the metadata of many works at once.
+// Nil fields are left untouched. Setting an AdditionalMetadata key to nil removes it.
+// ClearMadeWith empties "made with" before AddMadeWith is applied.
+type MetadataPatch struct {
+	AddTags            []string               `json:"addTags"`
+	RemoveTags         []string               `json:"removeTags"`
+	WIP                *bool                  `json:"wip"`
+	Private            *bool                  `json:"private"`
+	ClearMadeWith      bool                   `json:"clearMadeWith"`
+	AddMadeWith        []string               `json:"addMadeWith"`
+	RemoveMadeWith     []string               `json:"removeMadeWith"`
+	AdditionalMetadata map[string]interface{} `json:"additionalMetadata"`
+}
+
+// BulkUpdateResult tells whether a work was updated by BulkUpdateMetadata, and why not if it failed.
+// Skipped works were left untouched because their description.md changed since they were built, see DescriptionsChangedError.
+type BulkUpdateResult struct {
+	WorkID  string `json:"workID"`
+	Changed bool   `json:"changed"`
+	Skipped bool   `json:"skipped"`
+	Error   string `json:"error"`
+}
+
+// removeReferences removes the values of list that are, or refer to the same tag or technology as, any of removed.
+// sameEntry tells whether two names refer to the same tag or technology.
+func removeReferences(list []string, removed []string, sameEntry func(a, b string) bool) []string {
+	result := make([]string, 0, len(list))
+	for _, value := range list {
+		keep := true
+		for _, name := range removed {
+			if strings.EqualFold(value, name) || sameEntry(value, name) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// addReferences adds the values of added that are not already in list, directly or through an alias.
+func addReferences(list []string, added []string, sameEntry func(a, b string) bool) []string {
+	result := append([]string{}, list...)
+	for _, name := range added {
+		present := false
+		for _, value := range result {
+			if strings.EqualFold(value, name) || sameEntry(value, name) {
+				present = true
+				break
+			}
+		}
+		if !present {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// Apply applies the patch to metadata, resolving tag and technology aliases with env.
+// It returns true if anything changed.
+func (patch MetadataPatch) Apply(metadata *ortfodb.WorkMetadata, env queryEnvironment) bool {
+	before := *metadata
+	sameTag := func(a, b string) bool {
+		for _, tag := range env.tags {
+			if tag.ReferredToBy(a) && tag.ReferredToBy(b) {
+				return true
+			}
+		}
+		return false
+	}
+	sameTechnology := func(a, b string) bool {
+		for _, technology := range env.technologies {
+			if technology.ReferredToBy(a) && technology.ReferredToBy(b) {
+				return true
+			}
+		}
+		return false
+	}
+
+	metadata.Tags = addReferences(removeReferences(metadata.Tags, patch.RemoveTags, sameTag), patch.AddTags, sameTag)
+	madeWith := metadata.MadeWith
+	if patch.ClearMadeWith {
+		madeWith = make([]string, 0)
+	}
+	metadata.MadeWith = addReferences(removeReferences(madeWith, patch.RemoveMadeWith, sameTechnology), patch.AddMadeWith, sameTechnology)
+	if patch.WIP != nil {
+		metadata.WIP = *patch.WIP
+	}
+	if patch.Private != nil {
+		metadata.Private = *patch.Private
+	}
+
+	additionalMetadataChanged := false
+	if len(patch.AdditionalMetadata) > 0 {
+		additional := make(map[string]interface{}, len(metadata.AdditionalMetadata))
+		for key, value := range metadata.AdditionalMetadata {
+			additional[key] = value
+		}
+		for key, value := range patch.AdditionalMetadata {
+			current, exists := additional[key]
+			if value == nil {
+				if exists {
+					delete(additional, key)
+					additionalMetadataChanged = true
+				}
+			} else if !exists || !reflect.DeepEqual(current, value) {
+				additional[key] = value
+				additionalMetadataChanged = true
+			}
+		}
+		metadata.AdditionalMetadata = additional
+	}
+
+	return additionalMetadataChanged ||
+		before.WIP != metadata.WIP ||
+		before.Private != metadata.Private ||
+		strings.Join(before.Tags, "\x00") != strings.Join(metadata.Tags, "\x00") ||
+		strings.Join(before.MadeWith, "\x00") != strings.Join(metadata.MadeWith, "\x00")
+}
+
+// BulkUpdateMetadata applies patch to the works with the given IDs and writes back the ones that changed.
+// A work that cannot be updated does not prevent the others from being updated: each work gets its own result.
+// Works whose description.md changed since they were built are skipped, so that these changes are not overwritten.
+func (settings *Settings) BulkUpdateMetadata(workIDs []string, patch MetadataPatch) ([]BulkUpdateResult, error) {
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("while loading database: %w", err)
+	}
+	env := newQueryEnvironment()
+
+	results := make([]BulkUpdateResult, 0, len(workIDs))
+	anyChanged := false
+	for _, workID := range workIDs {
+		result := BulkUpdateResult{WorkID: workID}
+		work, ok := db[workID]
+		if !ok {
+			result.Error = fmt.Sprintf("no work with ID %q in the database", workID)
+			results = append(results, result)
+			continue
+		}
+		descriptionChanged, err := settings.descriptionChanged(work, workID)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		if descriptionChanged {
+			result.Skipped = true
+			result.Error = DescriptionsChangedError{WorkIDs: []string{workID}}.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.Changed = patch.Apply(&work.Metadata, env)
+		if result.Changed {
+			err = settings.writebackWork(&work, workID)
+			if err != nil {
+				result.Changed = false
+				result.Error = fmt.Sprintf("while writing back description: %s", err)
+			} else {
+				db[workID] = work
+				anyChanged = true
+			}
+		}
+		results = append(results, result)
+	}
+
+	if anyChanged {
+		ctx.WriteDatabase(db, ctx.Flags, ctx.OutputDatabaseFile, db.Partial())
+	}
+	return results, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// commands are the command-line subcommands. Other arguments are ignored and open the graphical interface,
+// since launchers pass arguments of their own (e.g. -psn_… on macOS).
+var commands = []string{"report", "help", "-h", "--help"}
+
+// isCommand returns true if args (without the program name) start with a command-line subcommand.
+func isCommand(args []string) bool {
+	return len(args) > 0 && containsString(commands, args[0])
+}
+
+// runCommand runs the command-line subcommand described by args (without the program name), and returns the exit code.
+func runCommand(args []string) int {
+	switch args[0] {
+	case "report":
+		flags := flag.NewFlagSet("report", flag.ContinueOnError)
+		format := flags.String("format", "markdown", fmt.Sprintf("format of the report, one of %v", ReportFormats))
+		output := flags.String("output", "", "file to write the report to (default: standard output)")
+		if err := flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if _, err := os.Stat(ConfigurationDirectory("portfolio-database", "database.json")); os.IsNotExist(err) {
+			fmt.Fprintln(os.Stderr, "error: the database has not been built yet, open ortfo to build it")
+			return 1
+		}
+		if err := settings.ExportPortfolioReport(*format, *output); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			return 1
+		}
+		return 0
+	case "help", "-h", "--help":
+		fmt.Println("Usage: ortfo [command]\n\nWithout a command, opens the graphical interface.\n\nCommands:\n  report [--format markdown|json] [--output FILE]    Summarize the state of the portfolio")
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "error: unknown command %q, run with help to see available commands\n", args[0])
+	return 2
+}
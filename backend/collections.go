@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	ortfodb "github.com/ortfo/db"
+	"gopkg.in/yaml.v2"
+)
+
+// LoadCollections reads collections.yaml. Collections are returned in the order they are declared in.
+func LoadCollections() ([]Collection, error) {
+	raw, err := os.ReadFile(ConfigurationDirectory("portfolio-database", "collections.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("while reading collections: %w", err)
+	}
+
+	// Collections are stored as a mapping of IDs to collections: read it as a MapSlice to keep the order.
+	var collectionsByID yaml.MapSlice
+	err = yaml.Unmarshal(raw, &collectionsByID)
+	if err != nil {
+		return nil, fmt.Errorf("while parsing collections: %w", err)
+	}
+
+	collections := make([]Collection, 0, len(collectionsByID))
+	for _, item := range collectionsByID {
+		id, ok := item.Key.(string)
+		if !ok {
+			return nil, fmt.Errorf("while parsing collections: collection ID %v is not a string", item.Key)
+		}
+		var collection Collection
+		// Round-trip through YAML to decode the value into a Collection
+		rawCollection, err := yaml.Marshal(item.Value)
+		if err != nil {
+			return nil, fmt.Errorf("while parsing collection %s: %w", id, err)
+		}
+		err = yaml.Unmarshal(rawCollection, &collection)
+		if err != nil {
+			return nil, fmt.Errorf("while parsing collection %s: %w", id, err)
+		}
+		collection.ID = id
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
+
+// SaveCollections writes collections to collections.yaml, keeping their order.
+func SaveCollections(collections []Collection) error {
+	collectionsByID := make(yaml.MapSlice, 0, len(collections))
+	seen := make(map[string]bool)
+	for _, c := range collections {
+		if c.ID == "" {
+			return fmt.Errorf("collection %v has no ID", c.Title)
+		}
+		if seen[c.ID] {
+			return fmt.Errorf("there are multiple collections with ID %q", c.ID)
+		}
+		seen[c.ID] = true
+		collectionsByID = append(collectionsByID, yaml.MapItem{Key: c.ID, Value: c})
+	}
+	collectionsBytes, err := yaml.Marshal(collectionsByID)
+	if err != nil {
+		return fmt.Errorf("while converting to YAML: %w", err)
+	}
+
+	return os.WriteFile(ConfigurationDirectory("portfolio-database", "collections.yaml"), collectionsBytes, 0644)
+}
+
+// CollectionPreview describes which works a collection query selects, or why it is invalid.
+type CollectionPreview struct {
+	Count   int         `json:"count"`
+	WorkIDs []string    `json:"workIDs"`
+	Error   *QueryError `json:"error"`
+}
+
+// newQueryEnvironment loads the tags and technologies repositories, used to resolve aliases in queries.
+// Repositories that cannot be loaded are treated as empty.
+func newQueryEnvironment() queryEnvironment {
+	tags, err := LoadTags()
+	if err != nil {
+		ErrorToBrowser("while loading tags to evaluate collections: %s", err)
+	}
+	technologies, err := LoadTechnologies()
+	if err != nil {
+		ErrorToBrowser("while loading technologies to evaluate collections: %s", err)
+	}
+	return queryEnvironment{tags: tags, technologies: technologies}
+}
+
+// selectWorks returns the IDs of the works of db matched by query.
+// Works listed in order come first, in that order. The others follow, sorted by ID.
+func selectWorks(db ortfodb.Database, query CollectionQuery, env queryEnvironment, order []string) []string {
+	matched := make(map[string]bool)
+	for id, work := range db {
+		if query.Matches(work, env) {
+			matched[id] = true
+		}
+	}
+
+	workIDs := make([]string, 0, len(matched))
+	for _, id := range order {
+		if matched[id] {
+			workIDs = append(workIDs, id)
+			delete(matched, id)
+		}
+	}
+	rest := make([]string, 0, len(matched))
+	for id := range matched {
+		rest = append(rest, id)
+	}
+	sort.Strings(rest)
+	return append(workIDs, rest...)
+}
+
+// EvaluateCollection returns the IDs of the works included in the collection with the given ID.
+func (settings *Settings) EvaluateCollection(id string) ([]string, error) {
+	collections, err := LoadCollections()
+	if err != nil {
+		return nil, err
+	}
+	var collection Collection
+	found := false
+	for _, c := range collections {
+		if c.ID == id {
+			collection, found = c, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no collection with ID %q", id)
+	}
+
+	query, err := ParseCollectionQuery(collection.Includes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query for collection %s: %w", id, err)
+	}
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("while loading database: %w", err)
+	}
+	return selectWorks(db, query, newQueryEnvironment(), collection.Works), nil
+}
+
+// PreviewCollectionQuery evaluates a query that is being edited. order is the collection's manual ordering of works.
+// Syntax errors are reported in the preview instead of being returned, so that the UI can show where they are.
+func (settings *Settings) PreviewCollectionQuery(query string, order []string) (CollectionPreview, error) {
+	parsed, err := ParseCollectionQuery(query)
+	if queryError, ok := err.(QueryError); ok {
+		return CollectionPreview{WorkIDs: make([]string, 0), Error: &queryError}, nil
+	}
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return CollectionPreview{}, fmt.Errorf("while loading database: %w", err)
+	}
+	workIDs := selectWorks(db, parsed, newQueryEnvironment(), order)
+	return CollectionPreview{Count: len(workIDs), WorkIDs: workIDs}, nil
+}
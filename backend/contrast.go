@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseHexColor parses colors written as RRGGBB or RGB, with or without a leading #.
+func parseHexColor(hex string) (r, g, b uint8, err error) {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, fmt.Errorf("invalid color %q: expected 3 or 6 hexadecimal digits", hex)
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid color %q: %w", hex, err)
+	}
+	return uint8(value >> 16), uint8(value >> 8), uint8(value), nil
+}
+
+// relativeLuminance computes the relative luminance of a color, as defined by WCAG 2.
+func relativeLuminance(r, g, b uint8) float64 {
+	linearize := func(channel uint8) float64 {
+		c := float64(channel) / 255
+		if c <= 0.03928 {
+			return c / 12.92
+		}
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linearize(r) + 0.7152*linearize(g) + 0.0722*linearize(b)
+}
+
+// ContrastRatio computes the WCAG 2 contrast ratio between two hex colors, from 1 (no contrast) to 21 (black on white).
+func ContrastRatio(foreground string, background string) (float64, error) {
+	fr, fg, fb, err := parseHexColor(foreground)
+	if err != nil {
+		return 0, err
+	}
+	br, bg, bb, err := parseHexColor(background)
+	if err != nil {
+		return 0, err
+	}
+	lighter, darker := relativeLuminance(fr, fg, fb), relativeLuminance(br, bg, bb)
+	if darker > lighter {
+		lighter, darker = darker, lighter
+	}
+	return (lighter + 0.05) / (darker + 0.05), nil
+}
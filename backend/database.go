@@ -93,6 +93,24 @@ func (settings *Settings) RebuildDatabase() error {
 	if err != nil {
 		return fmt.Errorf("couldn't build the portfolio's database: %w", err)
 	}
+	db, err := ortfodb.LoadDatabase(ConfigurationDirectory("portfolio-database", "database.json"), true)
+	if err != nil {
+		return fmt.Errorf("while loading database to validate metadata: %w", err)
+	}
+	settings.validateMetadataAfterRebuild(db)
+	return nil
+}
+
+// RebuildWork rebuilds the work with ID workID, and validates the metadata of the rebuilt database.
+func (settings *Settings) RebuildWork(workID string) error {
+	if workID == "" {
+		return fmt.Errorf("workID is empty")
+	}
+	built, err := ctx.BuildSome(workID, projectsFolder(), ctx.OutputDatabaseFile, ctx.Flags, *ctx.Config)
+	if err != nil {
+		return err
+	}
+	settings.validateMetadataAfterRebuild(built)
 	return nil
 }
 
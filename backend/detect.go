@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// MetadataEvidence explains why a technology or tag was suggested.
+// Kind is "file" when Path matches the file pattern Rule, "content" when the file at Path satisfies the "CONTENT in PATH" expression Rule,
+// and "technology" when the work is made with the technology Rule.
+type MetadataEvidence struct {
+	Kind string `json:"kind"`
+	Rule string `json:"rule"`
+	Path string `json:"path,omitempty"`
+}
+
+// MetadataSuggestion is a value to add to a work's "made with" or tags.
+type MetadataSuggestion struct {
+	Value    string             `json:"value"`
+	Evidence []MetadataEvidence `json:"evidence"`
+}
+
+// MetadataSuggestions lists the technologies and tags detected in a work's project folder that it does not declare yet.
+type MetadataSuggestions struct {
+	MadeWith []MetadataSuggestion `json:"madeWith"`
+	Tags     []MetadataSuggestion `json:"tags"`
+}
+
+// projectFiles lists the paths, relative to root and slash-separated, of every file and directory in root.
+// Dependencies and version control directories are skipped.
+func projectFiles(root string) (paths []string, isDir map[string]bool, err error) {
+	isDir = make(map[string]bool)
+	err = fs.WalkDir(os.DirFS(root), ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "." {
+			return nil
+		}
+		if entry.IsDir() {
+			switch entry.Name() {
+			case "node_modules", ".venv", ".git", ".ortfo":
+				return fs.SkipDir
+			}
+		}
+		paths = append(paths, path)
+		isDir[path] = entry.IsDir()
+		return nil
+	})
+	return
+}
+
+// detectEvidence looks for files matching the gitignore-style patterns of files, and for files satisfying the "CONTENT in PATH" expressions of contentConditions,
+// in the same way ortfodb does.
+func detectEvidence(root string, paths []string, isDir map[string]bool, files []string, contentConditions []string) ([]MetadataEvidence, error) {
+	evidence := make([]MetadataEvidence, 0)
+	for _, rule := range files {
+		pattern := gitignore.ParsePattern(rule, nil)
+		for _, path := range paths {
+			result := pattern.Match(strings.Split(path, "/"), isDir[path])
+			if result == gitignore.Exclude {
+				evidence = append(evidence, MetadataEvidence{Kind: "file", Rule: rule, Path: path})
+			}
+			if result != gitignore.NoMatch {
+				break
+			}
+		}
+	}
+
+	for _, rule := range contentConditions {
+		content, path, ok := strings.Cut(rule, " in ")
+		if !ok {
+			return evidence, fmt.Errorf("invalid autodetect expression %q: should be of the form CONTENT in PATH", rule)
+		}
+		contents, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return evidence, fmt.Errorf("while reading %s: %w", path, err)
+		}
+		if strings.Contains(string(contents), content) {
+			evidence = append(evidence, MetadataEvidence{Kind: "content", Rule: rule, Path: path})
+		}
+	}
+	return evidence, nil
+}
+
+// SuggestMetadata scans the project folder of the work with the given ID for the files and contents declared by technologies.yaml and tags.yaml,
+// and suggests technologies and tags that the work does not declare yet.
+func (settings *Settings) SuggestMetadata(workID string) (MetadataSuggestions, error) {
+	suggestions := MetadataSuggestions{MadeWith: make([]MetadataSuggestion, 0), Tags: make([]MetadataSuggestion, 0)}
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return suggestions, fmt.Errorf("while loading database: %w", err)
+	}
+	work, ok := db[workID]
+	if !ok {
+		return suggestions, fmt.Errorf("no work with ID %q in the database", workID)
+	}
+	technologies, err := LoadTechnologies()
+	if err != nil {
+		return suggestions, err
+	}
+	tags, err := LoadTags()
+	if err != nil {
+		return suggestions, err
+	}
+
+	root := JoinPaths(settings.ProjectsFolder, workID)
+	paths, isDir, err := projectFiles(root)
+	if err != nil {
+		return suggestions, fmt.Errorf("while listing files of %s: %w", root, err)
+	}
+
+	madeWith := append([]string{}, work.Metadata.MadeWith...)
+	for _, technology := range technologies {
+		if technology.ReferredToByAny(work.Metadata.MadeWith) {
+			continue
+		}
+		evidence, err := detectEvidence(root, paths, isDir, technology.Files, technology.Autodetect)
+		if err != nil {
+			return suggestions, fmt.Errorf("while detecting technology %s: %w", technology.Slug, err)
+		}
+		if len(evidence) > 0 {
+			suggestions.MadeWith = append(suggestions.MadeWith, MetadataSuggestion{Value: technology.Slug, Evidence: evidence})
+			madeWith = append(madeWith, technology.Slug)
+		}
+	}
+
+	for _, tag := range tags {
+		if tag.ReferredToByAny(work.Metadata.Tags) {
+			continue
+		}
+		evidence, err := detectEvidence(root, paths, isDir, tag.Detect.Files, tag.Detect.Search)
+		if err != nil {
+			return suggestions, fmt.Errorf("while detecting tag %s: %w", tag.displayName(), err)
+		}
+		// Technologies suggested above count too, so that accepting all suggestions is consistent.
+		for _, name := range tag.Detect.MadeWith {
+			for _, technology := range technologies {
+				if technology.ReferredToBy(name) && technology.ReferredToByAny(madeWith) {
+					evidence = append(evidence, MetadataEvidence{Kind: "technology", Rule: name})
+					break
+				}
+			}
+		}
+		if len(evidence) > 0 {
+			suggestions.Tags = append(suggestions.Tags, MetadataSuggestion{Value: tag.displayName(), Evidence: evidence})
+		}
+	}
+	return suggestions, nil
+}
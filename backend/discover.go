@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+// projectMarkers are files whose presence in a directory suggests that it contains a project.
+var projectMarkers = []string{
+	"Makefile", "Justfile", "CMakeLists.txt", "meson.build",
+	"package.json", "go.mod", "Cargo.toml", "pyproject.toml", "setup.py", "requirements.txt",
+	"pom.xml", "build.gradle", "build.gradle.kts", "composer.json", "Gemfile", "mix.exs", "pubspec.yaml",
+	"index.html",
+}
+
+// UndescribedProject is a directory of the projects folder that looks like a project but has no description yet.
+// Evidence lists what makes it look like a project.
+// Dates are of the form YYYY-MM-DD, and are empty if they could not be guessed.
+type UndescribedProject struct {
+	ID       string   `json:"id"`
+	Path     string   `json:"path"`
+	Title    string   `json:"title"`
+	Started  string   `json:"started"`
+	Finished string   `json:"finished"`
+	MadeWith []string `json:"madeWith"`
+	Evidence []string `json:"evidence"`
+}
+
+var patternMarkdownHeading = regexp.MustCompile(`^#\s+(.+?)\s*#*\s*$`)
+
+// findReadme returns the path to the README of the directory, or the empty string if it has none.
+func findReadme(directory string) string {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		name := strings.ToLower(entry.Name())
+		if !entry.IsDir() && (name == "readme" || strings.HasPrefix(name, "readme.")) {
+			return filepath.Join(directory, entry.Name())
+		}
+	}
+	return ""
+}
+
+// readmeTitle returns the first level-1 heading of a Markdown README, or the empty string if it has none.
+func readmeTitle(readmePath string) string {
+	file, err := os.Open(readmePath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	previous := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := patternMarkdownHeading.FindStringSubmatch(line); match != nil {
+			return match[1]
+		}
+		// Setext-style heading
+		if strings.TrimSpace(previous) != "" && len(strings.TrimSpace(line)) >= 2 && strings.Trim(strings.TrimSpace(line), "=") == "" {
+			return strings.TrimSpace(previous)
+		}
+		previous = line
+	}
+	return ""
+}
+
+// titleFromDirectoryName turns a directory name such as "my-cool_project" into "My Cool Project".
+func titleFromDirectoryName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' || r == '.' || r == ' ' })
+	for i, word := range words {
+		runes := []rune(word)
+		words[i] = strings.ToUpper(string(runes[0])) + string(runes[1:])
+	}
+	return strings.Join(words, " ")
+}
+
+// guessProjectDates returns the dates of the first and last commits of the project if it is a git repository,
+// or else the oldest and newest modification times of its files.
+func guessProjectDates(directory string, paths []string) (started string, finished string) {
+	if _, err := os.Stat(filepath.Join(directory, ".git")); err == nil {
+		first, errFirst := ortfodb.FirstGitCommitDate(directory)
+		last, errLast := ortfodb.LastGitCommitDate(directory)
+		if errFirst == nil && errLast == nil {
+			return first.Format("2006-01-02"), last.Format("2006-01-02")
+		}
+	}
+
+	var oldest, newest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(filepath.Join(directory, filepath.FromSlash(path)))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if oldest.IsZero() || info.ModTime().Before(oldest) {
+			oldest = info.ModTime()
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	if oldest.IsZero() {
+		return "", ""
+	}
+	return oldest.Format("2006-01-02"), newest.Format("2006-01-02")
+}
+
+// DiscoverUndescribedProjects finds directories of the projects folder that look like projects
+// (they are git repositories, have a README or build files) but have no .ortfo/description.md.
+// Each one is returned with a guessed title, dates and technologies.
+func (settings *Settings) DiscoverUndescribedProjects() ([]UndescribedProject, error) {
+	projects := make([]UndescribedProject, 0)
+	projectsFolder := JoinPaths(settings.ProjectsFolder)
+	entries, err := os.ReadDir(projectsFolder)
+	if err != nil {
+		return projects, fmt.Errorf("while listing projects folder: %w", err)
+	}
+	technologies, err := LoadTechnologies()
+	if err != nil {
+		ErrorToBrowser("while loading technologies to detect them in projects: %s", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		directory := filepath.Join(projectsFolder, entry.Name())
+		if _, err := os.Stat(filepath.Join(directory, ".ortfo", "description.md")); err == nil {
+			continue
+		}
+
+		evidence := make([]string, 0)
+		if _, err := os.Stat(filepath.Join(directory, ".git")); err == nil {
+			evidence = append(evidence, ".git")
+		}
+		readme := findReadme(directory)
+		if readme != "" {
+			evidence = append(evidence, filepath.Base(readme))
+		}
+		for _, marker := range projectMarkers {
+			if _, err := os.Stat(filepath.Join(directory, marker)); err == nil {
+				evidence = append(evidence, marker)
+			}
+		}
+		if len(evidence) == 0 {
+			continue
+		}
+
+		project := UndescribedProject{
+			ID:       entry.Name(),
+			Path:     directory,
+			MadeWith: make([]string, 0),
+			Evidence: evidence,
+		}
+		if readme != "" {
+			project.Title = readmeTitle(readme)
+		}
+		if project.Title == "" {
+			project.Title = titleFromDirectoryName(entry.Name())
+		}
+
+		paths, isDir, err := projectFiles(directory)
+		if err != nil {
+			ErrorToBrowser("while listing files of %s: %s", directory, err)
+		}
+		project.Started, project.Finished = guessProjectDates(directory, paths)
+		for _, technology := range technologies {
+			detected, err := detectEvidence(directory, paths, isDir, technology.Files, technology.Autodetect)
+			if err != nil {
+				ErrorToBrowser("while detecting technology %s in %s: %s", technology.Slug, directory, err)
+				continue
+			}
+			if len(detected) > 0 {
+				project.MadeWith = append(project.MadeWith, technology.Slug)
+			}
+		}
+		projects = append(projects, project)
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].ID < projects[j].ID
+	})
+	return projects, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// isRemoteSource returns true if source is not a local file, and would need to be downloaded.
+func isRemoteSource(source string) bool {
+	lower := strings.ToLower(source)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "//") || strings.HasPrefix(lower, "data:")
+}
+
+// resolveLocalSource returns the absolute path of the file that source refers to, in an HTML page saved in pageDirectory.
+func resolveLocalSource(source string, pageDirectory string) string {
+	source = strings.TrimPrefix(source, "file://")
+	source = strings.SplitN(strings.SplitN(source, "#", 2)[0], "?", 2)[0]
+	if unescaped, err := url.PathUnescape(source); err == nil {
+		source = unescaped
+	}
+	if filepath.IsAbs(source) {
+		return source
+	}
+	return filepath.Join(pageDirectory, filepath.FromSlash(source))
+}
+
+// markdownMediaEmbed returns the description.md syntax for a media block.
+func markdownMediaEmbed(alt string, source string, caption string) string {
+	alt = strings.Join(strings.Fields(alt), " ")
+	caption = strings.ReplaceAll(strings.Join(strings.Fields(caption), " "), `"`, "'")
+	if caption != "" {
+		return fmt.Sprintf("\n\n![%s](%s \"%s\")\n\n", alt, source, caption)
+	}
+	return fmt.Sprintf("\n\n![%s](%s)\n\n", alt, source)
+}
+
+// ImportHTML creates the content of the work with ID workID from a locally saved HTML page, such as a page of an old portfolio.
+// The content is created in the portfolio's first language.
+// Local images are copied into the work's media folder, remote ones are kept as is: nothing is downloaded.
+// Figures become media blocks, captioned by their <figcaption>.
+func (settings *Settings) ImportHTML(path string, workID string) error {
+	lang := "default"
+	if len(settings.PortfolioLanguages) > 0 {
+		lang = settings.PortfolioLanguages[0]
+	}
+	// Check that the content can be imported before copying images, so that a refused import leaves nothing behind.
+	work, err := settings.workToImportInto(workID, lang)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("while opening %s: %w", path, err)
+	}
+	defer file.Close()
+	document, err := goquery.NewDocumentFromReader(file)
+	if err != nil {
+		return fmt.Errorf("while parsing %s: %w", path, err)
+	}
+
+	root := document.Find("main, article").First()
+	if root.Length() == 0 {
+		root = document.Find("body")
+	}
+	root.Find("script, style, noscript, template, nav, footer, form, iframe").Remove()
+
+	// Copy local images, and remove the ones that do not exist
+	pageDirectory := filepath.Dir(path)
+	root.Find("img").EachWithBreak(func(_ int, image *goquery.Selection) bool {
+		source := strings.TrimSpace(image.AttrOr("src", ""))
+		if source == "" {
+			image.Remove()
+			return true
+		}
+		if isRemoteSource(source) {
+			return true
+		}
+		absolute := resolveLocalSource(source, pageDirectory)
+		if _, statErr := os.Stat(absolute); statErr != nil {
+			LogToBrowser("Image %s does not exist, skipping it", absolute)
+			image.Remove()
+			return true
+		}
+		var copied string
+		copied, err = settings.BringOutsideMedia(absolute, workID)
+		if err != nil {
+			return false
+		}
+		image.SetAttr("src", copied)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("while copying images of %s: %w", path, err)
+	}
+
+	converter := md.NewConverter("", true, nil)
+	converter.AddRules(
+		md.Rule{
+			Filter: []string{"figure"},
+			Replacement: func(content string, figure *goquery.Selection, options *md.Options) *string {
+				images := figure.Find("img")
+				if images.Length() == 0 {
+					return nil
+				}
+				caption := figure.Find("figcaption").Text()
+				embeds := ""
+				images.Each(func(_ int, image *goquery.Selection) {
+					embeds += markdownMediaEmbed(image.AttrOr("alt", ""), image.AttrOr("src", ""), caption)
+				})
+				return &embeds
+			},
+		},
+		md.Rule{
+			// Keep titles on images, since they become captions
+			Filter: []string{"img"},
+			Replacement: func(content string, image *goquery.Selection, options *md.Options) *string {
+				embed := markdownMediaEmbed(image.AttrOr("alt", ""), image.AttrOr("src", ""), image.AttrOr("title", ""))
+				embed = strings.TrimSpace(embed)
+				return &embed
+			},
+		},
+	)
+	markdown := converter.Convert(root)
+
+	// Pages without a level-1 heading get their <title> as the work's title
+	if root.Find("h1").Length() == 0 {
+		if title := strings.TrimSpace(document.Find("title").First().Text()); title != "" {
+			markdown = "# " + title + "\n\n" + markdown
+		}
+	}
+	markdown = normalizeReadmeHeadings(markdown)
+
+	return settings.importMarkdown(work, lang, markdown)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+// Strategies for removePortfolioLanguage
+const (
+	// Delete the content in the removed language.
+	RemoveLanguageDelete = "delete"
+	// Keep the content in the removed language as the default content, used for languages that have no content of their own.
+	RemoveLanguageKeepAsDefault = "default"
+)
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteWorks applies transform to every work of the database, and writes back the ones for which transform returns true.
+// It returns the paths to the descriptions of these works. With dryRun, nothing is written.
+// If the description.md of any of these works changed since it was built, nothing is written either,
+// and a DescriptionsChangedError lists them.
+func (settings *Settings) rewriteWorks(transform func(work *ortfodb.Work) bool, dryRun bool) ([]string, error) {
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("while loading database: %w", err)
+	}
+
+	changed := make([]string, 0)
+	rewritten := make(map[string]ortfodb.Work)
+	outdated := make([]string, 0)
+	for workID, work := range db {
+		if !transform(&work) {
+			continue
+		}
+		changed = append(changed, JoinPaths(settings.ProjectsFolder, workID, ".ortfo", "description.md"))
+		rewritten[workID] = work
+		descriptionChanged, err := settings.descriptionChanged(work, workID)
+		if err != nil {
+			return changed, err
+		}
+		if descriptionChanged {
+			outdated = append(outdated, workID)
+		}
+	}
+	sort.Strings(changed)
+	if len(outdated) > 0 {
+		sort.Strings(outdated)
+		return changed, DescriptionsChangedError{WorkIDs: outdated}
+	}
+	if dryRun {
+		return changed, nil
+	}
+
+	for workID, work := range rewritten {
+		err = settings.writebackWork(&work, workID)
+		if err != nil {
+			return changed, fmt.Errorf("while writing back description of %s: %w", workID, err)
+		}
+		db[workID] = work
+	}
+	ctx.WriteDatabase(db, ctx.Flags, ctx.OutputDatabaseFile, db.Partial())
+	return changed, nil
+}
+
+// rewriteCollections changes the keys of the localized fields of all collections, see changeKeys.
+func rewriteCollections(replaceMap map[string]string) error {
+	collections, err := LoadCollections()
+	if err != nil {
+		return err
+	}
+	for i := range collections {
+		collections[i].Title = changeKeys(collections[i].Title, replaceMap)
+		collections[i].Description = changeKeys(collections[i].Description, replaceMap)
+	}
+	return SaveCollections(collections)
+}
+
+// fixUIStateLanguage makes sure the UI state does not refer to a language that is not in the portfolio anymore.
+// renamedFrom is the former name of the UI's language, if it was renamed.
+func (settings *Settings) fixUIStateLanguage(renamedFrom string, renamedTo string) error {
+	state, err := settings.LoadUIState()
+	if err != nil {
+		return fmt.Errorf("while loading UI state: %w", err)
+	}
+	if renamedFrom != "" && state.Lang == renamedFrom {
+		state.Lang = renamedTo
+	}
+	if !containsString(settings.PortfolioLanguages, state.Lang) {
+		state.Lang = settings.PortfolioLanguages[0]
+	}
+	return SaveUIState(state)
+}
+
+// AddPortfolioLanguage adds lang to the portfolio's languages.
+// Works that were written without language markers get their content assigned to the portfolio's first language,
+// so that they can be translated.
+func (settings *Settings) AddPortfolioLanguage(lang string) error {
+	if lang == "" || lang == "default" {
+		return fmt.Errorf("invalid language %q", lang)
+	}
+	if containsString(settings.PortfolioLanguages, lang) {
+		return fmt.Errorf("the portfolio already has language %s", lang)
+	}
+
+	if len(settings.PortfolioLanguages) > 0 {
+		firstLanguage := settings.PortfolioLanguages[0]
+		_, err := settings.rewriteWorks(func(work *ortfodb.Work) bool {
+			_, hasDefault := work.Content["default"]
+			_, hasFirst := work.Content[firstLanguage]
+			if !hasDefault || hasFirst {
+				return false
+			}
+			work.Content = changeKeys(work.Content, map[string]string{"default": firstLanguage})
+			return true
+		}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	settings.PortfolioLanguages = append(settings.PortfolioLanguages, lang)
+	return SaveSettings(*settings)
+}
+
+// RemovePortfolioLanguage removes lang from the portfolio's languages, and handles the works' content in that language
+// according to strategy (one of RemoveLanguageDelete or RemoveLanguageKeepAsDefault).
+func (settings *Settings) RemovePortfolioLanguage(lang string, strategy string) error {
+	if !containsString(settings.PortfolioLanguages, lang) {
+		return fmt.Errorf("the portfolio has no language %s", lang)
+	}
+	if len(settings.PortfolioLanguages) == 1 {
+		return fmt.Errorf("cannot remove %s: it is the only language of the portfolio", lang)
+	}
+
+	var newKey string
+	switch strategy {
+	case RemoveLanguageDelete:
+		newKey = ""
+	case RemoveLanguageKeepAsDefault:
+		newKey = "default"
+	default:
+		return fmt.Errorf("unknown strategy %q, valid strategies are %q and %q", strategy, RemoveLanguageDelete, RemoveLanguageKeepAsDefault)
+	}
+
+	// Check that everything can be changed before writing anything,
+	// so that a failure does not leave works without the language while the settings still have it.
+	conflicts := make([]string, 0)
+	_, err := settings.rewriteWorks(func(work *ortfodb.Work) bool {
+		if _, ok := work.Content[lang]; !ok {
+			return false
+		}
+		if _, hasDefault := work.Content["default"]; hasDefault && newKey == "default" {
+			conflicts = append(conflicts, work.ID)
+		}
+		return true
+	}, true)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("cannot keep %s as the default content: %s already have default content", lang, strings.Join(conflicts, ", "))
+	}
+	if _, err := LoadCollections(); err != nil {
+		return fmt.Errorf("while loading collections: %w", err)
+	}
+
+	languages := make([]string, 0, len(settings.PortfolioLanguages)-1)
+	for _, language := range settings.PortfolioLanguages {
+		if language != lang {
+			languages = append(languages, language)
+		}
+	}
+	updatedSettings := *settings
+	updatedSettings.PortfolioLanguages = languages
+	if err := ValidateSettings(updatedSettings); err != nil {
+		return fmt.Errorf("settings would not be valid anymore: %w", err)
+	}
+
+	_, err = settings.rewriteWorks(func(work *ortfodb.Work) bool {
+		if _, ok := work.Content[lang]; !ok {
+			return false
+		}
+		work.Content = changeKeys(work.Content, map[string]string{lang: newKey})
+		return true
+	}, false)
+	if err != nil {
+		return err
+	}
+
+	err = rewriteCollections(map[string]string{lang: ""})
+	if err != nil {
+		return fmt.Errorf("while updating collections: %w", err)
+	}
+
+	if lang == settings.PortfolioLanguages[0] {
+		// Snapshots record the paragraphs of the reference language, which is not the same language anymore.
+		err = (translationSnapshots{}).save()
+	} else {
+		err = renameTranslationSnapshots(map[string]string{lang: ""})
+	}
+	if err != nil {
+		return fmt.Errorf("while updating translation snapshots: %w", err)
+	}
+
+	settings.PortfolioLanguages = languages
+	err = SaveSettings(*settings)
+	if err != nil {
+		return err
+	}
+	return settings.fixUIStateLanguage("", "")
+}
+
+// RenameLanguage renames the portfolio language oldLang to newLang, in the settings, works, collections and UI state.
+func (settings *Settings) RenameLanguage(oldLang string, newLang string) error {
+	if !containsString(settings.PortfolioLanguages, oldLang) {
+		return fmt.Errorf("the portfolio has no language %s", oldLang)
+	}
+	if newLang == "" || newLang == "default" {
+		return fmt.Errorf("invalid language %q", newLang)
+	}
+	if containsString(settings.PortfolioLanguages, newLang) {
+		return fmt.Errorf("the portfolio already has language %s", newLang)
+	}
+
+	replaceMap := map[string]string{oldLang: newLang}
+
+	// Check that everything can be changed before writing anything, so that a failure does not leave the portfolio half-renamed.
+	_, err := settings.rewriteWorks(func(work *ortfodb.Work) bool {
+		_, ok := work.Content[oldLang]
+		return ok
+	}, true)
+	if err != nil {
+		return err
+	}
+	if _, err := LoadCollections(); err != nil {
+		return fmt.Errorf("while loading collections: %w", err)
+	}
+
+	languages := make([]string, 0, len(settings.PortfolioLanguages))
+	for _, language := range settings.PortfolioLanguages {
+		if language == oldLang {
+			language = newLang
+		}
+		languages = append(languages, language)
+	}
+	updatedSettings := *settings
+	updatedSettings.PortfolioLanguages = languages
+	if err := ValidateSettings(updatedSettings); err != nil {
+		return fmt.Errorf("settings would not be valid anymore: %w", err)
+	}
+
+	_, err = settings.rewriteWorks(func(work *ortfodb.Work) bool {
+		if _, ok := work.Content[oldLang]; !ok {
+			return false
+		}
+		work.Content = changeKeys(work.Content, replaceMap)
+		return true
+	}, false)
+	if err != nil {
+		return err
+	}
+
+	err = rewriteCollections(replaceMap)
+	if err != nil {
+		return fmt.Errorf("while updating collections: %w", err)
+	}
+
+	err = renameTranslationSnapshots(replaceMap)
+	if err != nil {
+		return fmt.Errorf("while updating translation snapshots: %w", err)
+	}
+
+	settings.PortfolioLanguages = languages
+	err = SaveSettings(*settings)
+	if err != nil {
+		return err
+	}
+	return settings.fixUIStateLanguage(oldLang, newLang)
+}
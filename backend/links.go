@@ -0,0 +1,387 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+type LinkStatus string
+
+const (
+	LinkBroken     LinkStatus = "broken"
+	LinkRedirected LinkStatus = "redirected"
+)
+
+// LinkProblem is a link that is broken or redirects elsewhere.
+// Language and BlockID are empty for links that are not in a work's content, such as external sites.
+type LinkProblem struct {
+	URL      string     `json:"url"`
+	Status   LinkStatus `json:"status"`
+	Language string     `json:"language"`
+	BlockID  string     `json:"blockID"`
+	// StatusCode is the HTTP status code the link responded with, or 0 if it is not an external link or could not be reached.
+	StatusCode  int    `json:"statusCode"`
+	RedirectsTo string `json:"redirectsTo"`
+	Message     string `json:"message"`
+}
+
+// LinkCheckReport holds the problematic links of each checked work, by work ID.
+// ExternalSites is only filled when the whole portfolio is checked.
+type LinkCheckReport struct {
+	Works         map[string][]LinkProblem `json:"works"`
+	ExternalSites []LinkProblem            `json:"externalSites"`
+}
+
+// externalLinkResult is the outcome of requesting an external URL. An empty status means the link is fine.
+type externalLinkResult struct {
+	status      LinkStatus
+	statusCode  int
+	redirectsTo string
+	message     string
+	checkedAt   time.Time
+}
+
+// LinkChecker checks external links, with at most Concurrency requests at a time.
+// HTTP responses are cached for CacheDuration, so that checking works again does not hit the same sites repeatedly.
+type LinkChecker struct {
+	Client        *http.Client
+	Concurrency   int
+	CacheDuration time.Duration
+
+	mutex sync.Mutex
+	cache map[string]externalLinkResult
+}
+
+// NewLinkChecker returns a LinkChecker that does not follow redirects, so that they can be reported.
+func NewLinkChecker() *LinkChecker {
+	return &LinkChecker{
+		Client: &http.Client{
+			Timeout: 15 * time.Second,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Concurrency:   8,
+		CacheDuration: 1 * time.Hour,
+	}
+}
+
+// linkChecker is shared between calls to CheckLinks, so that its cache is too.
+var linkChecker = NewLinkChecker()
+
+func (checker *LinkChecker) cached(link string) (externalLinkResult, bool) {
+	checker.mutex.Lock()
+	defer checker.mutex.Unlock()
+	result, ok := checker.cache[link]
+	if !ok || time.Since(result.checkedAt) > checker.CacheDuration {
+		return externalLinkResult{}, false
+	}
+	return result, true
+}
+
+func (checker *LinkChecker) store(link string, result externalLinkResult) {
+	checker.mutex.Lock()
+	defer checker.mutex.Unlock()
+	if checker.cache == nil {
+		checker.cache = make(map[string]externalLinkResult)
+	}
+	checker.cache[link] = result
+}
+
+// request sends a HEAD request to link, falling back to GET for servers that do not support HEAD.
+func (checker *LinkChecker) request(link string) (*http.Response, error) {
+	response, err := checker.Client.Head(link)
+	if err == nil && response.StatusCode != http.StatusMethodNotAllowed && response.StatusCode != http.StatusNotImplemented {
+		return response, nil
+	}
+	if err == nil {
+		response.Body.Close()
+	}
+	return checker.Client.Get(link)
+}
+
+func (checker *LinkChecker) check(link string) externalLinkResult {
+	if result, ok := checker.cached(link); ok {
+		return result
+	}
+
+	result := externalLinkResult{checkedAt: time.Now()}
+	response, err := checker.request(link)
+	if err != nil {
+		// Network errors and timeouts may be temporary: do not cache them, so that the link is requested again next time.
+		result.status = LinkBroken
+		result.message = fmt.Sprintf("could not be reached: %s", err)
+		return result
+	}
+	response.Body.Close()
+	result.statusCode = response.StatusCode
+	switch {
+	case response.StatusCode >= 300 && response.StatusCode < 400:
+		result.status = LinkRedirected
+		if location, err := response.Location(); err == nil {
+			result.redirectsTo = location.String()
+		}
+		result.message = fmt.Sprintf("redirects to %s", result.redirectsTo)
+	case response.StatusCode >= 400:
+		result.status = LinkBroken
+		result.message = fmt.Sprintf("responded with %s", response.Status)
+	}
+	checker.store(link, result)
+	return result
+}
+
+// CheckExternal requests every link, at most Concurrency at a time, and returns the results by link.
+func (checker *LinkChecker) CheckExternal(links []string) map[string]externalLinkResult {
+	results := make(map[string]externalLinkResult, len(links))
+	var resultsMutex sync.Mutex
+	var wait sync.WaitGroup
+	concurrency := checker.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	seen := make(map[string]bool)
+	for _, link := range links {
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		wait.Add(1)
+		go func(link string) {
+			defer wait.Done()
+			slots <- struct{}{}
+			result := checker.check(link)
+			<-slots
+			resultsMutex.Lock()
+			results[link] = result
+			resultsMutex.Unlock()
+		}(link)
+	}
+	wait.Wait()
+	return results
+}
+
+// workLink is a link found in a work's content.
+type workLink struct {
+	url      string
+	language string
+	blockID  string
+}
+
+// linkOccurence is where an external link was found.
+type linkOccurence struct {
+	workID string
+	link   workLink
+}
+
+var patternHref = regexp.MustCompile(`href="([^"]*)"`)
+
+func hrefs(content ortfodb.HTMLString) []string {
+	links := make([]string, 0)
+	for _, match := range patternHref.FindAllStringSubmatch(string(content), -1) {
+		links = append(links, html.UnescapeString(match[1]))
+	}
+	return links
+}
+
+// workLinks returns the links of paragraphs, link blocks, titles and footnotes of every language of work.
+func workLinks(work ortfodb.Work) []workLink {
+	links := make([]workLink, 0)
+	languages := make([]string, 0, len(work.Content))
+	for lang := range work.Content {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+
+	for _, lang := range languages {
+		content := work.Content[lang]
+		for _, link := range hrefs(content.Title) {
+			links = append(links, workLink{link, lang, ""})
+		}
+		for _, block := range content.Blocks {
+			switch {
+			case block.Type.IsParagraph():
+				for _, link := range hrefs(block.Content) {
+					links = append(links, workLink{link, lang, block.ID})
+				}
+			case block.Type.IsLink():
+				links = append(links, workLink{block.URL, lang, block.ID})
+			}
+		}
+		footnoteNames := make([]string, 0, len(content.Footnotes))
+		for name := range content.Footnotes {
+			footnoteNames = append(footnoteNames, name)
+		}
+		sort.Strings(footnoteNames)
+		for _, name := range footnoteNames {
+			for _, link := range hrefs(content.Footnotes[name]) {
+				links = append(links, workLink{link, lang, ""})
+			}
+		}
+	}
+	return links
+}
+
+// isExternalLink returns true for HTTP(S) links, including protocol-relative ones.
+func isExternalLink(parsed *url.URL) bool {
+	return parsed.Scheme == "http" || parsed.Scheme == "https" || (parsed.Scheme == "" && parsed.Host != "")
+}
+
+// externalURL returns the URL to request for an external link. Protocol-relative links are requested over HTTPS.
+func externalURL(parsed *url.URL) string {
+	if parsed.Scheme == "" {
+		withScheme := *parsed
+		withScheme.Scheme = "https"
+		return withScheme.String()
+	}
+	return parsed.String()
+}
+
+// checkLocalLink checks a link that is not external: /work-id links must point to an existing work ID or alias,
+// and relative links must point to a file that exists, relative to the work's .ortfo folder.
+// It returns a description of what is wrong, or the empty string.
+func (settings *Settings) checkLocalLink(db ortfodb.Database, workID string, parsed *url.URL) string {
+	if strings.HasPrefix(parsed.Path, "/") {
+		target := strings.SplitN(strings.Trim(parsed.Path, "/"), "/", 2)[0]
+		if target == "" {
+			return ""
+		}
+		if _, ok := db[target]; ok {
+			return ""
+		}
+		for _, work := range db {
+			if containsString(work.Metadata.Aliases, target) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("no work with ID or alias %q", target)
+	}
+
+	if parsed.Path == "" {
+		return ""
+	}
+	if _, err := os.Stat(JoinPaths(settings.ProjectsFolder, workID, ".ortfo", parsed.Path)); err != nil {
+		return fmt.Sprintf("file %s does not exist", parsed.Path)
+	}
+	return ""
+}
+
+// checkLinks checks the links of the works with the given IDs, or of every work and external site if workIDs is empty.
+func (settings *Settings) checkLinks(checker *LinkChecker, db ortfodb.Database, sites []ExternalSite, workIDs []string) (LinkCheckReport, error) {
+	report := LinkCheckReport{Works: make(map[string][]LinkProblem), ExternalSites: make([]LinkProblem, 0)}
+	wholePortfolio := len(workIDs) == 0
+	if wholePortfolio {
+		for workID := range db {
+			workIDs = append(workIDs, workID)
+		}
+		sort.Strings(workIDs)
+	}
+
+	// Local links are checked right away, external ones are collected to be checked concurrently.
+	external := make(map[string][]linkOccurence)
+	externalURLs := make([]string, 0)
+	for _, workID := range workIDs {
+		work, ok := db[workID]
+		if !ok {
+			return report, fmt.Errorf("no work with ID %q in the database", workID)
+		}
+		report.Works[workID] = make([]LinkProblem, 0)
+		for _, link := range workLinks(work) {
+			if link.url == "" || strings.HasPrefix(link.url, "#") {
+				continue
+			}
+			parsed, err := url.Parse(link.url)
+			if err != nil {
+				report.Works[workID] = append(report.Works[workID], LinkProblem{
+					URL: link.url, Status: LinkBroken, Language: link.language, BlockID: link.blockID,
+					Message: fmt.Sprintf("malformed URL: %s", err),
+				})
+				continue
+			}
+			switch {
+			case isExternalLink(parsed):
+				target := externalURL(parsed)
+				if _, ok := external[target]; !ok {
+					externalURLs = append(externalURLs, target)
+				}
+				external[target] = append(external[target], linkOccurence{workID, link})
+			case parsed.Scheme == "":
+				if message := settings.checkLocalLink(db, workID, parsed); message != "" {
+					report.Works[workID] = append(report.Works[workID], LinkProblem{
+						URL: link.url, Status: LinkBroken, Language: link.language, BlockID: link.blockID, Message: message,
+					})
+				}
+			}
+			// Other schemes (mailto:, tel:…) cannot be checked.
+		}
+	}
+
+	if wholePortfolio {
+		for _, site := range sites {
+			if site.URL != "" && !containsString(externalURLs, site.URL) {
+				externalURLs = append(externalURLs, site.URL)
+			}
+		}
+	}
+
+	results := checker.CheckExternal(externalURLs)
+	for _, link := range externalURLs {
+		result := results[link]
+		if result.status == "" {
+			continue
+		}
+		for _, occurence := range external[link] {
+			report.Works[occurence.workID] = append(report.Works[occurence.workID], LinkProblem{
+				URL:         occurence.link.url,
+				Status:      result.status,
+				Language:    occurence.link.language,
+				BlockID:     occurence.link.blockID,
+				StatusCode:  result.statusCode,
+				RedirectsTo: result.redirectsTo,
+				Message:     result.message,
+			})
+		}
+		if wholePortfolio {
+			for _, site := range sites {
+				if site.URL == link {
+					report.ExternalSites = append(report.ExternalSites, LinkProblem{
+						URL:         link,
+						Status:      result.status,
+						StatusCode:  result.statusCode,
+						RedirectsTo: result.redirectsTo,
+						Message:     fmt.Sprintf("%s: %s", site.Name, result.message),
+					})
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// CheckLinks reports broken and redirected links in the works with the given IDs.
+// With no work IDs, every work and the external sites of sites.yaml are checked.
+func (settings *Settings) CheckLinks(workIDs []string) (LinkCheckReport, error) {
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return LinkCheckReport{}, fmt.Errorf("while loading database: %w", err)
+	}
+	sites := make([]ExternalSite, 0)
+	if len(workIDs) == 0 {
+		read, err := ReadExternalSites()
+		if err != nil {
+			return LinkCheckReport{}, fmt.Errorf("while reading external sites: %w", err)
+		}
+		sites = read.Sites
+	}
+	return settings.checkLinks(linkChecker, db, sites, workIDs)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+// linkTestServer stands in for external sites. It counts requests per path and the maximum number of concurrent requests.
+type linkTestServer struct {
+	*httptest.Server
+	mutex       sync.Mutex
+	requests    map[string]int
+	inFlight    int32
+	maxInFlight int32
+}
+
+func newLinkTestServer(t *testing.T) *linkTestServer {
+	server := &linkTestServer{requests: make(map[string]int)}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight := atomic.AddInt32(&server.inFlight, 1)
+		defer atomic.AddInt32(&server.inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&server.maxInFlight)
+			if inFlight <= max || atomic.CompareAndSwapInt32(&server.maxInFlight, max, inFlight) {
+				break
+			}
+		}
+		server.mutex.Lock()
+		server.requests[r.URL.Path]++
+		server.mutex.Unlock()
+		time.Sleep(20 * time.Millisecond)
+
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (server *linkTestServer) requestsTo(path string) int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.requests[path]
+}
+
+func workWithLinks(id string, links ...string) ortfodb.Work {
+	content := ""
+	for _, link := range links {
+		content += fmt.Sprintf(`<a href="%s">link</a> `, link)
+	}
+	paragraph := ortfodb.ContentBlock{ID: "paragraph", Type: "paragraph"}
+	paragraph.Content = ortfodb.HTMLString(content)
+	return ortfodb.Work{
+		ID:      id,
+		Content: ortfodb.LocalizableContent{"en": {Blocks: []ortfodb.ContentBlock{paragraph}}},
+	}
+}
+
+func problemsByURL(problems []LinkProblem) map[string]LinkProblem {
+	byURL := make(map[string]LinkProblem)
+	for _, problem := range problems {
+		byURL[problem.URL] = problem
+	}
+	return byURL
+}
+
+func TestCheckLinksReportsBrokenAndRedirectedLinks(t *testing.T) {
+	server := newLinkTestServer(t)
+	db := ortfodb.Database{
+		"work":  workWithLinks("work", server.URL+"/ok", server.URL+"/moved", server.URL+"/gone", server.URL+"/no-head", "/other", "/missing", "#anchor", "mailto:someone@example.com"),
+		"other": workWithLinks("other"),
+	}
+	settings := Settings{ProjectsFolder: t.TempDir()}
+
+	report, err := settings.checkLinks(NewLinkChecker(), db, nil, []string{"work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := problemsByURL(report.Works["work"])
+	if len(problems) != 3 {
+		t.Errorf("expected 3 problems, got %+v", report.Works["work"])
+	}
+	if problem := problems[server.URL+"/gone"]; problem.Status != LinkBroken || problem.StatusCode != http.StatusNotFound {
+		t.Errorf("expected /gone to be broken with a 404, got %+v", problem)
+	}
+	if problem := problems[server.URL+"/moved"]; problem.Status != LinkRedirected || problem.RedirectsTo != server.URL+"/ok" {
+		t.Errorf("expected /moved to redirect to /ok, got %+v", problem)
+	}
+	if problem := problems["/missing"]; problem.Status != LinkBroken || problem.BlockID != "paragraph" || problem.Language != "en" {
+		t.Errorf("expected /missing to be a broken internal link, got %+v", problem)
+	}
+}
+
+func TestCheckLinksReportsExternalSites(t *testing.T) {
+	server := newLinkTestServer(t)
+	sites := []ExternalSite{{Name: "Gone", URL: server.URL + "/gone"}, {Name: "Fine", URL: server.URL + "/ok"}}
+	settings := Settings{ProjectsFolder: t.TempDir()}
+
+	report, err := settings.checkLinks(NewLinkChecker(), ortfodb.Database{}, sites, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.ExternalSites) != 1 || report.ExternalSites[0].URL != server.URL+"/gone" {
+		t.Errorf("expected only the Gone site to be reported, got %+v", report.ExternalSites)
+	}
+}
+
+func TestCheckLinksCachesResponses(t *testing.T) {
+	server := newLinkTestServer(t)
+	db := ortfodb.Database{"work": workWithLinks("work", server.URL+"/gone", server.URL+"/ok")}
+	settings := Settings{ProjectsFolder: t.TempDir()}
+	checker := NewLinkChecker()
+
+	for i := 0; i < 3; i++ {
+		if _, err := settings.checkLinks(checker, db, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests := server.requestsTo("/gone"); requests != 1 {
+		t.Errorf("expected /gone to be requested once, got %d requests", requests)
+	}
+
+	checker.CacheDuration = 0
+	if _, err := settings.checkLinks(checker, db, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if requests := server.requestsTo("/gone"); requests != 2 {
+		t.Errorf("expected /gone to be requested again once the cache expired, got %d requests", requests)
+	}
+}
+
+func TestCheckLinksDoesNotCacheNetworkErrors(t *testing.T) {
+	server := newLinkTestServer(t)
+	unreachable := server.URL + "/ok"
+	server.Close()
+	checker := NewLinkChecker()
+
+	if result := checker.check(unreachable); result.status != LinkBroken {
+		t.Fatalf("expected an unreachable link to be broken, got %+v", result)
+	}
+	if _, cached := checker.cached(unreachable); cached {
+		t.Errorf("expected network errors not to be cached")
+	}
+}
+
+func TestCheckLinksLimitsConcurrency(t *testing.T) {
+	server := newLinkTestServer(t)
+	links := make([]string, 0)
+	for i := 0; i < 12; i++ {
+		links = append(links, fmt.Sprintf("%s/page-%d", server.URL, i))
+	}
+	db := ortfodb.Database{"work": workWithLinks("work", links...)}
+	settings := Settings{ProjectsFolder: t.TempDir()}
+	checker := NewLinkChecker()
+	checker.Concurrency = 3
+
+	report, err := settings.checkLinks(checker, db, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Works["work"]) != 0 {
+		t.Errorf("expected no problems, got %+v", report.Works["work"])
+	}
+	if max := atomic.LoadInt32(&server.maxInFlight); max > 3 {
+		t.Errorf("expected at most 3 concurrent requests, got %d", max)
+	}
+}
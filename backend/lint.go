@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+	LintInfo    LintSeverity = "info"
+)
+
+// LintIssue is a problem found in a work's content. WorkID, Language and BlockID let the UI jump to where the issue is.
+type LintIssue struct {
+	Severity LintSeverity `json:"severity"`
+	// Rule is a short machine-readable identifier of the kind of issue (e.g. missing-alt).
+	Rule     string `json:"rule"`
+	WorkID   string `json:"workID"`
+	Language string `json:"language"`
+	BlockID  string `json:"blockID"`
+	Message  string `json:"message"`
+}
+
+var (
+	// rendered footnote references, as output by the markdown parser
+	patternFootnoteReferenceHTML = regexp.MustCompile(`href="#fn:([^"]+)"`)
+	// footnote references left as-is by the markdown parser, because they have no matching definition
+	patternFootnoteReferenceRaw = regexp.MustCompile(`\[\^([^\s\]]+)\]`)
+)
+
+// LintWork reports issues in the given work's content for each of the given languages.
+func LintWork(work ortfodb.Work, languages []string) []LintIssue {
+	issues := make([]LintIssue, 0)
+	issue := func(severity LintSeverity, rule string, language string, blockID string, message string, args ...interface{}) {
+		issues = append(issues, LintIssue{
+			Severity: severity,
+			Rule:     rule,
+			WorkID:   work.ID,
+			Language: language,
+			BlockID:  blockID,
+			Message:  fmt.Sprintf(message, args...),
+		})
+	}
+
+	for _, lang := range languages {
+		content, ok := work.Content[lang]
+		if !ok {
+			issue(LintWarning, "missing-language", lang, "", "Work has no content in %s", lang)
+			continue
+		}
+
+		if strings.TrimSpace(content.Title.String()) == "" {
+			issue(LintError, "empty-title", lang, "", "Title is empty")
+		}
+
+		blockIDs := make(map[string]bool)
+		for _, block := range content.Blocks {
+			blockIDs[block.ID] = true
+			switch {
+			case block.Type.IsMedia():
+				if strings.TrimSpace(block.Alt) == "" {
+					issue(LintWarning, "missing-alt", lang, block.ID, "Media %s has no alt text", block.RelativeSource)
+				}
+				if strings.TrimSpace(block.Caption) == "" {
+					issue(LintInfo, "missing-caption", lang, block.ID, "Media %s has no caption", block.RelativeSource)
+				}
+			case block.Type.IsParagraph():
+				for _, reference := range footnoteReferences(string(block.Content)) {
+					if _, ok := content.Footnotes[reference]; !ok {
+						issue(LintError, "dangling-footnote", lang, block.ID, "Footnote [^%s] is referenced but never defined", reference)
+					}
+				}
+			}
+		}
+
+		for _, cell := range content.Layout.BlockIDs() {
+			if cell != "" && !blockIDs[cell] {
+				issue(LintError, "dangling-layout-cell", lang, cell, "Layout refers to block %s, which does not exist", cell)
+			}
+		}
+	}
+	return issues
+}
+
+// footnoteReferences returns the names of all footnotes referenced in the given paragraph HTML.
+func footnoteReferences(paragraph string) []string {
+	references := make([]string, 0)
+	for _, pattern := range []*regexp.Regexp{patternFootnoteReferenceHTML, patternFootnoteReferenceRaw} {
+		for _, match := range pattern.FindAllStringSubmatch(paragraph, -1) {
+			references = append(references, match[1])
+		}
+	}
+	return references
+}
+
+// LintPortfolio reports issues in every work, for every language of the portfolio.
+// Issues are sorted by work, then by language, then by severity.
+func (settings *Settings) LintPortfolio() ([]LintIssue, error) {
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("while loading database: %w", err)
+	}
+
+	issues := make([]LintIssue, 0)
+	schema, err := LoadMetadataSchema()
+	if err != nil {
+		// Works cannot be checked against a schema that is invalid, but the rest of the lint still applies.
+		issues = append(issues, LintIssue{Severity: LintError, Rule: "metadata-schema", Message: err.Error()})
+		schema = nil
+	}
+
+	for _, work := range db {
+		issues = append(issues, LintWork(work, settings.PortfolioLanguages)...)
+		issues = append(issues, ValidateWorkMetadata(work, schema, settings.PortfolioLanguages)...)
+	}
+
+	severityRank := map[LintSeverity]int{LintError: 0, LintWarning: 1, LintInfo: 2}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].WorkID != issues[j].WorkID {
+			return issues[i].WorkID < issues[j].WorkID
+		}
+		if issues[i].Language != issues[j].Language {
+			return issues[i].Language < issues[j].Language
+		}
+		return severityRank[issues[i].Severity] < severityRank[issues[j].Severity]
+	})
+	return issues, nil
+}
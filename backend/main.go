@@ -44,6 +44,9 @@ func projectsFolder() string {
 
 func main() {
 	settings, _ = LoadSettings()
+	if isCommand(os.Args[1:]) {
+		os.Exit(runCommand(os.Args[1:]))
+	}
 	fmt.Printf("Settings: %#v\n", settings)
 	go startFilesystemServer(settings.ProjectsFolder)
 	err := startWebview()
@@ -95,45 +98,130 @@ var BackendFunctions = map[string]interface{}{
 	"rebuildDatabase": func() error {
 		return settings.RebuildDatabase()
 	},
+	"metadataSchema": func() ([]MetadataFieldSchema, error) {
+		return LoadMetadataSchema()
+	},
+	"databaseStaleness": func() (DatabaseStaleness, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return DatabaseStaleness{}, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.CheckDatabaseStaleness()
+	},
+	"getStartupStalenessCheck": func() StartupStalenessCheck {
+		return StartupStalenessCheckState()
+	},
+	"rebuildStaleWorks": func() (DatabaseStaleness, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return DatabaseStaleness{}, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.RebuildStaleWorks()
+	},
+	"checkLinks": func(workIDs []string) (LinkCheckReport, error) {
+		return settings.CheckLinks(workIDs)
+	},
 	"rebuildWork": func(workID string) error {
-		if workID == "" {
-			return fmt.Errorf("workID is empty")
+		settings, err := LoadSettings()
+		if err != nil {
+			return fmt.Errorf("while loading settings: %w", err)
 		}
-		_, err := ctx.BuildSome(workID, projectsFolder(), ctx.OutputDatabaseFile, ctx.Flags, *ctx.Config)
-		return err
+
+		return settings.RebuildWork(workID)
+	},
+	"getMetadataIssues": func() []LintIssue {
+		return MetadataIssues()
 	},
 	"analyzeMedia": func(workID string, mediaEmbed ortfodb.Media) (ortfodb.Media, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return ortfodb.Media{}, fmt.Errorf("while loading settings: %w", err)
+		}
+
 		_, media, _, err := ctx.AnalyzeMediaFile(workID, mediaEmbed)
 		if err != nil {
 			return ortfodb.Media{}, fmt.Errorf("while analyzing media: %w", err)
 		}
+		if settings.MediaOptimization.Enabled {
+			// The media itself was analyzed fine: report optimization failures without losing the analysis.
+			if _, err := settings.OptimizeMedia(workID, media); err != nil {
+				ErrorToBrowser("while optimizing %s: %s", media.RelativeSource, err)
+			}
+		}
 		return media, nil
 	},
-	"writeback": func(description ortfodb.Work, workID string) error {
+	"analyzeAllMedia": func(workIDs []string) ([]MediaAnalysisResult, error) {
 		settings, err := LoadSettings()
 		if err != nil {
-			return fmt.Errorf("while loading settings: %w", err)
+			return nil, fmt.Errorf("while loading settings: %w", err)
 		}
 
-		return Writeback(settings, description, workID)
+		return settings.AnalyzeAllMedia(workIDs)
 	},
-	"writeTags": func(tags []ortfodb.Tag) error {
-		spew.Dump(tags)
-		tagsBytes, err := yaml.Marshal(tags)
+	"getMediaAnalysisProgress": func() MediaAnalysisProgress {
+		return MediaAnalysisProgressState()
+	},
+	"optimizeMedia": func(workID string, media ortfodb.Media) (MediaOptimization, error) {
+		settings, err := LoadSettings()
 		if err != nil {
-			return fmt.Errorf("while converting to YAML: %w", err)
+			return MediaOptimization{}, fmt.Errorf("while loading settings: %w", err)
 		}
 
-		return os.WriteFile(ConfigurationDirectory("portfolio-database", "tags.yaml"), tagsBytes, 0644)
+		return settings.OptimizeMedia(workID, media)
 	},
-	"writeTechnologies": func(technologies []ortfodb.Technology) error {
-		tagsBytes, err := yaml.Marshal(technologies)
+	"scanMediaPrivacy": func() ([]MediaPrivacyLeak, error) {
+		settings, err := LoadSettings()
 		if err != nil {
-			ErrorToBrowser(fmt.Sprintf("while converting to YAML: %v", err))
-			return fmt.Errorf("while converting to YAML: %w", err)
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.ScanMediaPrivacy()
+	},
+	"scrubMedia": func(paths []string) error {
+		settings, err := LoadSettings()
+		if err != nil {
+			return fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.ScrubMedia(paths)
+	},
+	"writeback": func(description ortfodb.Work, workID string) error {
+		settings, err := LoadSettings()
+		if err != nil {
+			return fmt.Errorf("while loading settings: %w", err)
 		}
 
-		return os.WriteFile(ConfigurationDirectory("portfolio-database", "technologies.yaml"), tagsBytes, 0644)
+		db, err := settings.LoadDatabase()
+		if err != nil {
+			return fmt.Errorf("while loading database: %w", err)
+		}
+		err = Writeback(settings, description, workID)
+		if err != nil {
+			return err
+		}
+		return settings.recordTranslations(workID, db[workID], description)
+	},
+	"readTags": func() (TagsReadResult, error) {
+		return ReadTags()
+	},
+	"writeTags": func(tags []Tag) error {
+		spew.Dump(tags)
+		return SaveTags(tags)
+	},
+	"readTechnologies": func() (TechnologiesReadResult, error) {
+		return ReadTechnologies()
+	},
+	"writeTechnologies": func(technologies []Technology) error {
+		err := SaveTechnologies(technologies)
+		if err != nil {
+			ErrorToBrowser(err.Error())
+		}
+		return err
+	},
+	"readExternalSites": func() (ExternalSitesReadResult, error) {
+		return ReadExternalSites()
 	},
 	"writeExternalSites": func(externalSites []ExternalSite) error {
 		sitesBytes, err := yaml.Marshal(externalSites)
@@ -143,17 +231,107 @@ var BackendFunctions = map[string]interface{}{
 
 		return os.WriteFile(ConfigurationDirectory("portfolio-database", "sites.yaml"), sitesBytes, 0644)
 	},
+	"readCollections": func() ([]Collection, error) {
+		return LoadCollections()
+	},
 	"writeCollection": func(collections []Collection) error {
-		collectionsByID := make(map[string]Collection)
-		for _, c := range collections {
-			collectionsByID[c.ID] = c
+		return SaveCollections(collections)
+	},
+	"evaluateCollection": func(id string) ([]string, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
 		}
-		collectionsBytes, err := yaml.Marshal(collectionsByID)
+
+		return settings.EvaluateCollection(id)
+	},
+	"previewCollectionQuery": func(query string, order []string) (CollectionPreview, error) {
+		settings, err := LoadSettings()
 		if err != nil {
-			return fmt.Errorf("while converting to YAML: %w", err)
+			return CollectionPreview{}, fmt.Errorf("while loading settings: %w", err)
 		}
 
-		return os.WriteFile(ConfigurationDirectory("portfolio-database", "collections.yaml"), collectionsBytes, 0644)
+		return settings.PreviewCollectionQuery(query, order)
+	},
+	"suggestMetadata": func(workID string) (MetadataSuggestions, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return MetadataSuggestions{}, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.SuggestMetadata(workID)
+	},
+	"discoverUndescribedProjects": func() ([]UndescribedProject, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.DiscoverUndescribedProjects()
+	},
+	"importFromReadme": func(workID string, lang string) error {
+		settings, err := LoadSettings()
+		if err != nil {
+			return fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.ImportFromReadme(workID, lang)
+	},
+	"importHTML": func(path string, workID string) error {
+		settings, err := LoadSettings()
+		if err != nil {
+			return fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.ImportHTML(path, workID)
+	},
+	"importSpreadsheet": func(path string, mapping SpreadsheetMapping, dryRun bool) ([]SpreadsheetRowResult, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.ImportSpreadsheet(path, mapping, dryRun)
+	},
+	"taxonomyStats": func() (TaxonomyStats, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return TaxonomyStats{}, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.TaxonomyStats()
+	},
+	"renameTag": func(oldName string, newName string, dryRun bool) ([]string, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.RenameTag(oldName, newName, dryRun)
+	},
+	"mergeTags": func(sources []string, target string, dryRun bool) ([]string, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.MergeTags(sources, target, dryRun)
+	},
+	"renameTechnology": func(oldName string, newName string, dryRun bool) ([]string, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.RenameTechnology(oldName, newName, dryRun)
+	},
+	"mergeTechnologies": func(sources []string, target string, dryRun bool) ([]string, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.MergeTechnologies(sources, target, dryRun)
 	},
 	"saveState": func(state UIState) error {
 		err := SaveUIState(state)
@@ -230,6 +408,13 @@ var BackendFunctions = map[string]interface{}{
 		}
 		return settings.DeleteWorks(workIDs)
 	},
+	"bulkUpdateMetadata": func(workIDs []string, patch MetadataPatch) ([]BulkUpdateResult, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+		return settings.BulkUpdateMetadata(workIDs, patch)
+	},
 	"rawDescription": func(workID string) (string, error) {
 		settings, err := LoadSettings()
 		if err != nil {
@@ -249,8 +434,113 @@ var BackendFunctions = map[string]interface{}{
 	"clearThumbnails": func() error {
 		return os.RemoveAll(ConfigurationDirectory("portfolio-database", "media"))
 	},
-	"extractColors": func(imagePath string) (colors ortfodb.ColorPalette, err error) {
-		return ortfodb.ExtractColors(ConfigurationDirectory("portfolio-database", imagePath))
+	"extractColors": func(mediaPath string) (colors ortfodb.ColorPalette, err error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return colors, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		candidates, err := settings.ExtractPalettes("", mediaPath, 1)
+		if err != nil || len(candidates) == 0 {
+			return colors, err
+		}
+		return candidates[0].Palette, nil
+	},
+	"extractPalettes": func(workID string, mediaPath string, count int) ([]PaletteCandidate, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.ExtractPalettes(workID, mediaPath, count)
+	},
+	"applyColors": func(workID string, palette ortfodb.ColorPalette) error {
+		settings, err := LoadSettings()
+		if err != nil {
+			return fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.ApplyColors(workID, palette)
+	},
+	"checkAccessibility": func(workID string) (AccessibilityReport, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return AccessibilityReport{}, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.CheckAccessibility(workID)
+	},
+	"accessibilityReport": func() ([]AccessibilityReport, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.AccessibilityFailures()
+	},
+	"lintPortfolio": func() ([]LintIssue, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.LintPortfolio()
+	},
+	"translationStatus": func() (map[string]map[string]TranslationStatus, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return nil, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.TranslationStatus()
+	},
+	"translateWork": func(workID string, fromLang string, toLang string, provider string) error {
+		settings, err := LoadSettings()
+		if err != nil {
+			return fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.TranslateWork(workID, fromLang, toLang, provider)
+	},
+	"addPortfolioLanguage": func(lang string) error {
+		settings, err := LoadSettings()
+		if err != nil {
+			return fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.AddPortfolioLanguage(lang)
+	},
+	"removePortfolioLanguage": func(lang string, strategy string) error {
+		settings, err := LoadSettings()
+		if err != nil {
+			return fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.RemovePortfolioLanguage(lang, strategy)
+	},
+	"renameLanguage": func(oldLang string, newLang string) error {
+		settings, err := LoadSettings()
+		if err != nil {
+			return fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.RenameLanguage(oldLang, newLang)
+	},
+	"portfolioReport": func() (PortfolioReport, error) {
+		settings, err := LoadSettings()
+		if err != nil {
+			return PortfolioReport{}, fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.PortfolioReport()
+	},
+	"exportPortfolioReport": func(format string, outputPath string) error {
+		settings, err := LoadSettings()
+		if err != nil {
+			return fmt.Errorf("while loading settings: %w", err)
+		}
+
+		return settings.ExportPortfolioReport(format, outputPath)
 	},
 	"newDir": func(path string) error {
 		return os.MkdirAll(path, 0755)
@@ -284,8 +574,8 @@ var BackendFunctions = map[string]interface{}{
 func startWebview() error {
 	typescript := tsreflect.New(tsreflect.ExportEverything())
 	typescript.Add(reflect.TypeOf(ortfodb.Work{}))
-	typescript.Add(reflect.TypeOf(ortfodb.Tag{}))
-	typescript.Add(reflect.TypeOf(ortfodb.Technology{}))
+	typescript.Add(reflect.TypeOf(Tag{}))
+	typescript.Add(reflect.TypeOf(Technology{}))
 	typescript.Add(reflect.TypeOf(ortfodb.ProgressInfoEvent{}))
 	typescript.Add(reflect.TypeOf(DirEntry{}))
 	typescript.Add(reflect.TypeOf(UIState{}))
@@ -294,6 +584,31 @@ func startWebview() error {
 	typescript.Add(reflect.TypeOf(Collection{}))
 	typescript.Add(reflect.TypeOf(ExternalSite{}))
 	typescript.Add(reflect.TypeOf(ortfodb.Database{}))
+	typescript.Add(reflect.TypeOf(MediaAnalysisProgress{}))
+	typescript.Add(reflect.TypeOf(MediaAnalysisResult{}))
+	typescript.Add(reflect.TypeOf(MediaOptimization{}))
+	typescript.Add(reflect.TypeOf(MediaPrivacyLeak{}))
+	typescript.Add(reflect.TypeOf(PaletteCandidate{}))
+	typescript.Add(reflect.TypeOf(AccessibilityReport{}))
+	typescript.Add(reflect.TypeOf(LintIssue{}))
+	typescript.Add(reflect.TypeOf(TranslationStatus{}))
+	typescript.Add(reflect.TypeOf(CollectionPreview{}))
+	typescript.Add(reflect.TypeOf(TaxonomyProblem{}))
+	typescript.Add(reflect.TypeOf(TagsReadResult{}))
+	typescript.Add(reflect.TypeOf(TechnologiesReadResult{}))
+	typescript.Add(reflect.TypeOf(ExternalSitesReadResult{}))
+	typescript.Add(reflect.TypeOf(TaxonomyStats{}))
+	typescript.Add(reflect.TypeOf(MetadataSuggestions{}))
+	typescript.Add(reflect.TypeOf(UndescribedProject{}))
+	typescript.Add(reflect.TypeOf(SpreadsheetMapping{}))
+	typescript.Add(reflect.TypeOf(SpreadsheetRowResult{}))
+	typescript.Add(reflect.TypeOf(MetadataPatch{}))
+	typescript.Add(reflect.TypeOf(BulkUpdateResult{}))
+	typescript.Add(reflect.TypeOf(MetadataFieldSchema{}))
+	typescript.Add(reflect.TypeOf(PortfolioReport{}))
+	typescript.Add(reflect.TypeOf(DatabaseStaleness{}))
+	typescript.Add(reflect.TypeOf(StartupStalenessCheck{}))
+	typescript.Add(reflect.TypeOf(LinkCheckReport{}))
 
 	ortfodb.LogFilePath = ConfigurationDirectory("ortfodb.log")
 	ortfodb.PrependDateToLogs = true
@@ -333,7 +648,8 @@ func startWebview() error {
 		}
 	}
 	wd, _ := os.Getwd()
-	os.WriteFile(filepath.Join(wd, "../frontend/backend.generated.ts"), []byte(typescript.DeclarationsTypeScript()), 0644)
+	os.WriteFile(filepath.Join(wd, "../frontend/backend.generated.ts"), []byte(withLocalizableStringType(typescript.DeclarationsTypeScript())), 0644)
+	go settings.checkStalenessAtStartup()
 	w.Run()
 	return nil
 }
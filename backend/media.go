@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -53,7 +54,40 @@ func startFilesystemServer(directory string) error {
 
 // BringOutsideMedia copies media files from outside the media folder to the media folder,
 // assuming that the media file belongs to the work with ID belongsTo.
-// It returns the path to the media file in the media folder.
-func BringOutsideMedia(source string, belongsTo string) (string, error) {
-	return "Not implemented yet", fmt.Errorf("not implemented yet")
+// It returns the path to the media file, relative to the work's .ortfo folder, as used in descriptions.
+// If the media folder already contains an identical file with the same name, it is reused.
+func (settings *Settings) BringOutsideMedia(source string, belongsTo string) (string, error) {
+	mediaFolder := JoinPaths(settings.ProjectsFolder, belongsTo, ".ortfo", "media")
+	err := os.MkdirAll(mediaFolder, 0755)
+	if err != nil {
+		return "", fmt.Errorf("couldn't create media folder: %w", err)
+	}
+
+	sourceHash, err := hashFile(source)
+	if err != nil {
+		return "", fmt.Errorf("while reading %s: %w", source, err)
+	}
+	extension := filepath.Ext(source)
+	name := strings.TrimSuffix(filepath.Base(source), extension)
+	filename := name + extension
+	for i := 2; ; i++ {
+		destinationHash, err := hashFile(filepath.Join(mediaFolder, filename))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err == nil && destinationHash == sourceHash {
+			return "media/" + filename, nil
+		}
+		filename = fmt.Sprintf("%s-%d%s", name, i, extension)
+	}
+
+	contents, err := os.ReadFile(source)
+	if err != nil {
+		return "", fmt.Errorf("while reading %s: %w", source, err)
+	}
+	err = os.WriteFile(filepath.Join(mediaFolder, filename), contents, 0644)
+	if err != nil {
+		return "", fmt.Errorf("while copying %s to the media folder: %w", source, err)
+	}
+	return "media/" + filename, nil
 }
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+// replaceValues replaces the values of list that replace maps to something, and removes duplicates that result from it.
+// It returns the new list and whether anything changed.
+func replaceValues(list []string, replace func(value string) (string, bool)) ([]string, bool) {
+	result := make([]string, 0, len(list))
+	changed := false
+	for _, value := range list {
+		if replacement, ok := replace(value); ok {
+			value = replacement
+			changed = true
+		}
+		if stringsLooselyMatch(value, result...) {
+			changed = true
+			continue
+		}
+		result = append(result, value)
+	}
+	return result, changed
+}
+
+// renameIn renames oldName to newName in s, in every language.
+func (s LocalizableString) renameIn(oldName string, newName string) LocalizableString {
+	if !s.IsLocalized() {
+		if strings.EqualFold(s.Plain, oldName) {
+			s.Plain = newName
+		}
+		return s
+	}
+	renamed := make(Localized[string], len(s.Localized))
+	for lang, value := range s.Localized {
+		if strings.EqualFold(value, oldName) {
+			value = newName
+		}
+		renamed[lang] = value
+	}
+	return LocalizableString{Localized: renamed}
+}
+
+func renameInList(list []string, oldName string, newName string) []string {
+	renamed, _ := replaceValues(list, func(value string) (string, bool) {
+		return newName, strings.EqualFold(value, oldName)
+	})
+	return renamed
+}
+
+// taxonomyFilePath returns the path of a file of the portfolio database, as reported in the list of changed files.
+func taxonomyFilePath(filename string) string {
+	return ConfigurationDirectory("portfolio-database", filename)
+}
+
+// taxonomyEntry is a tag or a technology.
+type taxonomyEntry interface {
+	ReferredToBy(name string) bool
+	names() []string
+}
+
+// mergeTaxonomy merges the entries referred to by sources into the entry referred to by target, see MergeTags.
+// kind names entries in error messages, and aliases returns a pointer to an entry's aliases.
+// It returns the new entries, whether they differ from entries, and a function that tells whether a name referred to a merged entry.
+func mergeTaxonomy[T taxonomyEntry](entries []T, sources []string, target string, kind string, aliases func(entry *T) *[]string) ([]T, bool, func(name string) bool, error) {
+	targetIndex := -1
+	for i, entry := range entries {
+		if entry.ReferredToBy(target) {
+			targetIndex = i
+		}
+	}
+	if targetIndex == -1 {
+		return nil, false, nil, fmt.Errorf("no %s is named %q", kind, target)
+	}
+
+	merged := entries[targetIndex]
+	removed := make([]int, 0)
+	for i, entry := range entries {
+		if i == targetIndex {
+			continue
+		}
+		for _, source := range sources {
+			if entry.ReferredToBy(source) && !merged.ReferredToBy(source) {
+				removed = append(removed, i)
+				break
+			}
+		}
+	}
+	newAliases := make([]string, 0)
+	for _, i := range removed {
+		for _, name := range entries[i].names() {
+			if !merged.ReferredToBy(name) && !stringsLooselyMatch(name, newAliases...) {
+				newAliases = append(newAliases, name)
+			}
+		}
+	}
+	sort.Strings(newAliases)
+	mergedAliases := aliases(&merged)
+	*mergedAliases = append(append([]string{}, *mergedAliases...), newAliases...)
+
+	newEntries := make([]T, 0, len(entries)-len(removed))
+	for i, entry := range entries {
+		if i == targetIndex {
+			newEntries = append(newEntries, merged)
+		} else if !containsInt(removed, i) {
+			newEntries = append(newEntries, entry)
+		}
+	}
+
+	isSource := func(name string) bool {
+		if strings.EqualFold(name, target) {
+			return false
+		}
+		if stringsLooselyMatch(name, sources...) {
+			return true
+		}
+		for _, i := range removed {
+			if entries[i].ReferredToBy(name) {
+				return true
+			}
+		}
+		return false
+	}
+	return newEntries, len(removed) > 0 || len(newAliases) > 0, isSource, nil
+}
+
+func containsInt(list []int, value int) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RenameTag renames oldName to newName in the tag it refers to (as a singular, plural or alias),
+// and in the tags of every work that uses it.
+// It returns the paths of the files that were changed, or would be changed with dryRun.
+func (settings *Settings) RenameTag(oldName string, newName string, dryRun bool) ([]string, error) {
+	if strings.TrimSpace(newName) == "" {
+		return nil, fmt.Errorf("the new name cannot be empty")
+	}
+	tags, err := LoadTags()
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i, tag := range tags {
+		if tag.ReferredToBy(oldName) {
+			index = i
+		} else if tag.ReferredToBy(newName) {
+			return nil, fmt.Errorf("%q already refers to tag %q", newName, tag.displayName())
+		}
+	}
+	if index == -1 {
+		return nil, fmt.Errorf("no tag is named %q", oldName)
+	}
+
+	tag := tags[index]
+	tag.Singular = tag.Singular.renameIn(oldName, newName)
+	tag.Plural = tag.Plural.renameIn(oldName, newName)
+	tag.Aliases = renameInList(tag.Aliases, oldName, newName)
+	tags[index] = tag
+
+	changed, err := settings.rewriteWorks(func(work *ortfodb.Work) bool {
+		var changed bool
+		work.Metadata.Tags, changed = replaceValues(work.Metadata.Tags, func(value string) (string, bool) {
+			return newName, strings.EqualFold(value, oldName)
+		})
+		return changed
+	}, dryRun)
+	if err != nil {
+		return changed, err
+	}
+
+	changed = append([]string{taxonomyFilePath("tags.yaml")}, changed...)
+	if dryRun {
+		return changed, nil
+	}
+	return changed, SaveTags(tags)
+}
+
+// MergeTags merges the tags referred to by sources into the tag referred to by target:
+// the names of the source tags become aliases of the target tag, the source tags are removed,
+// and works that use any of them use target instead.
+// Sources that are not declared in tags.yaml are merged too, which is useful to clean up tags that were used by mistake.
+// It returns the paths of the files that were changed, or would be changed with dryRun.
+func (settings *Settings) MergeTags(sources []string, target string, dryRun bool) ([]string, error) {
+	tags, err := LoadTags()
+	if err != nil {
+		return nil, err
+	}
+	newTags, tagsChanged, isSource, err := mergeTaxonomy(tags, sources, target, "tag", func(tag *Tag) *[]string { return &tag.Aliases })
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := settings.rewriteWorks(func(work *ortfodb.Work) bool {
+		var changed bool
+		work.Metadata.Tags, changed = replaceValues(work.Metadata.Tags, func(value string) (string, bool) {
+			return target, isSource(value)
+		})
+		return changed
+	}, dryRun)
+	if err != nil {
+		return changed, err
+	}
+
+	if tagsChanged {
+		changed = append([]string{taxonomyFilePath("tags.yaml")}, changed...)
+	}
+	if dryRun {
+		return changed, nil
+	}
+	return changed, SaveTags(newTags)
+}
+
+// RenameTechnology renames oldName to newName in the technology it refers to (as a slug, name or alias),
+// and in the "made with" of every work that uses it.
+// It returns the paths of the files that were changed, or would be changed with dryRun.
+func (settings *Settings) RenameTechnology(oldName string, newName string, dryRun bool) ([]string, error) {
+	if strings.TrimSpace(newName) == "" {
+		return nil, fmt.Errorf("the new name cannot be empty")
+	}
+	technologies, err := LoadTechnologies()
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i, technology := range technologies {
+		if technology.ReferredToBy(oldName) {
+			index = i
+		} else if technology.ReferredToBy(newName) {
+			return nil, fmt.Errorf("%q already refers to technology %q", newName, technology.Slug)
+		}
+	}
+	if index == -1 {
+		return nil, fmt.Errorf("no technology is named %q", oldName)
+	}
+
+	technology := technologies[index]
+	if strings.EqualFold(technology.Slug, oldName) {
+		technology.Slug = newName
+	}
+	if strings.EqualFold(technology.Name, oldName) {
+		technology.Name = newName
+	}
+	technology.Aliases = renameInList(technology.Aliases, oldName, newName)
+	technologies[index] = technology
+
+	changed, err := settings.rewriteWorks(func(work *ortfodb.Work) bool {
+		var changed bool
+		work.Metadata.MadeWith, changed = replaceValues(work.Metadata.MadeWith, func(value string) (string, bool) {
+			return newName, strings.EqualFold(value, oldName)
+		})
+		return changed
+	}, dryRun)
+	if err != nil {
+		return changed, err
+	}
+
+	changed = append([]string{taxonomyFilePath("technologies.yaml")}, changed...)
+	if dryRun {
+		return changed, nil
+	}
+	return changed, SaveTechnologies(technologies)
+}
+
+// MergeTechnologies merges the technologies referred to by sources into the technology referred to by target.
+// See MergeTags.
+func (settings *Settings) MergeTechnologies(sources []string, target string, dryRun bool) ([]string, error) {
+	technologies, err := LoadTechnologies()
+	if err != nil {
+		return nil, err
+	}
+	newTechnologies, technologiesChanged, isSource, err := mergeTaxonomy(technologies, sources, target, "technology", func(technology *Technology) *[]string { return &technology.Aliases })
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := settings.rewriteWorks(func(work *ortfodb.Work) bool {
+		var changed bool
+		work.Metadata.MadeWith, changed = replaceValues(work.Metadata.MadeWith, func(value string) (string, bool) {
+			return target, isSource(value)
+		})
+		return changed
+	}, dryRun)
+	if err != nil {
+		return changed, err
+	}
+
+	if technologiesChanged {
+		changed = append([]string{taxonomyFilePath("technologies.yaml")}, changed...)
+	}
+	if dryRun {
+		return changed, nil
+	}
+	return changed, SaveTechnologies(newTechnologies)
+}
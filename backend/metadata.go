@@ -1,19 +1,184 @@
 package main
 
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
 type Localized[T any] map[string]T
 
+// LocalizableString is either a single string used for every language, or a string per language.
+// It is read from and written to YAML and JSON in whichever form it was declared in:
+//
+//	description: A plain string
+//	description:
+//	  en: A localized string
+//	  fr: Une chaîne localisée
+type LocalizableString struct {
+	Plain     string
+	Localized Localized[string]
+}
+
+// IsLocalized returns true if the string was declared per language.
+func (s LocalizableString) IsLocalized() bool {
+	return s.Localized != nil
+}
+
+// In returns the string in the given language.
+// Localized strings fall back to their "default" entry when they have none for lang.
+func (s LocalizableString) In(lang string) string {
+	if !s.IsLocalized() {
+		return s.Plain
+	}
+	if value, ok := s.Localized[lang]; ok {
+		return value
+	}
+	return s.Localized["default"]
+}
+
+// Values returns every distinct value of the string, in all languages.
+func (s LocalizableString) Values() []string {
+	if !s.IsLocalized() {
+		return []string{s.Plain}
+	}
+	values := make([]string, 0, len(s.Localized))
+	for _, value := range s.Localized {
+		if !containsString(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Empty returns true if the string has no non-blank value in any language.
+func (s LocalizableString) Empty() bool {
+	for _, value := range s.Values() {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *LocalizableString) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&s.Plain); err == nil {
+		s.Localized = nil
+		return nil
+	}
+	s.Plain = ""
+	return unmarshal(&s.Localized)
+}
+
+func (s LocalizableString) MarshalYAML() (interface{}, error) {
+	if s.IsLocalized() {
+		return s.Localized, nil
+	}
+	return s.Plain, nil
+}
+
+func (s *LocalizableString) UnmarshalJSON(raw []byte) error {
+	if err := json.Unmarshal(raw, &s.Plain); err == nil {
+		s.Localized = nil
+		return nil
+	}
+	s.Plain = ""
+	return json.Unmarshal(raw, &s.Localized)
+}
+
+func (s LocalizableString) MarshalJSON() ([]byte, error) {
+	if s.IsLocalized() {
+		return json.Marshal(s.Localized)
+	}
+	return json.Marshal(s.Plain)
+}
+
+// localizableStringTypeScript is the TypeScript type of LocalizableString's JSON form.
+// tsreflect does not know about custom marshalers, and would export the struct's fields instead.
+const localizableStringTypeScript = "export type LocalizableString = string | { [key in (string)]: (string) };"
+
+var patternLocalizableStringDeclaration = regexp.MustCompile(`(?m)^export (?:interface|type) LocalizableString\b.*$`)
+
+// withLocalizableStringType replaces the declaration of LocalizableString generated by tsreflect with localizableStringTypeScript.
+func withLocalizableStringType(declarations string) string {
+	if !patternLocalizableStringDeclaration.MatchString(declarations) {
+		return localizableStringTypeScript + "\n" + declarations
+	}
+	return patternLocalizableStringDeclaration.ReplaceAllLiteralString(declarations, localizableStringTypeScript)
+}
+
+// Tag mirrors ortfodb.Tag, with localizable names and description.
+type Tag struct {
+	Singular    LocalizableString `yaml:"singular" json:"singular"`
+	Plural      LocalizableString `yaml:"plural" json:"plural"`
+	Description LocalizableString `yaml:"description,omitempty" json:"description,omitempty"`
+	LearnMoreAt string            `yaml:"learn more at,omitempty" json:"learnMoreAt,omitempty"`
+	Aliases     []string          `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	Detect      struct {
+		Files    []string `yaml:"files,omitempty" json:"files,omitempty"`
+		Search   []string `yaml:"search,omitempty" json:"search,omitempty"`
+		MadeWith []string `yaml:"made with,omitempty" json:"madeWith,omitempty"`
+	} `yaml:"detect,omitempty" json:"detect,omitempty"`
+}
+
+// ReferredToBy returns true if name is one of the tag's names (in any language) or aliases.
+func (t Tag) ReferredToBy(name string) bool {
+	names := append(append(t.Singular.Values(), t.Plural.Values()...), t.Aliases...)
+	return stringsLooselyMatch(name, names...)
+}
+
+// ReferredToByAny returns true if the tag is referred to by any of names.
+func (t Tag) ReferredToByAny(names []string) bool {
+	for _, name := range names {
+		if t.ReferredToBy(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Technology mirrors ortfodb.Technology, with a localizable description.
+type Technology struct {
+	Slug        string            `yaml:"slug" json:"slug"`
+	Name        string            `yaml:"name" json:"name"`
+	By          string            `yaml:"by,omitempty" json:"by,omitempty"`
+	Description LocalizableString `yaml:"description,omitempty" json:"description,omitempty"`
+	LearnMoreAt string            `yaml:"learn more at,omitempty" json:"learnMoreAt,omitempty"`
+	Aliases     []string          `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	Files       []string          `yaml:"files,omitempty" json:"files,omitempty"`
+	Autodetect  []string          `yaml:"autodetect,omitempty" json:"autodetect,omitempty"`
+}
+
+// ReferredToBy returns true if name is the technology's slug, name or one of its aliases.
+func (t Technology) ReferredToBy(name string) bool {
+	return stringsLooselyMatch(name, append([]string{t.Slug, t.Name}, t.Aliases...)...)
+}
+
+// ReferredToByAny returns true if the technology is referred to by any of names.
+func (t Technology) ReferredToByAny(names []string) bool {
+	for _, name := range names {
+		if t.ReferredToBy(name) {
+			return true
+		}
+	}
+	return false
+}
+
 type ExternalSite struct {
-	Name     string `yaml:"name"`
-	URL      string `yaml:"url"`
-	Purpose  string `yaml:"purpose,omitempty"`
-	Username string `yaml:"username,omitempty"`
+	Name     string            `yaml:"name" json:"name"`
+	URL      string            `yaml:"url" json:"url"`
+	Purpose  LocalizableString `yaml:"purpose,omitempty" json:"purpose,omitempty"`
+	Username string            `yaml:"username,omitempty" json:"username,omitempty"`
 }
 
 type Collection struct {
-	ID          string            `yaml:"-"`
-	Title       Localized[string] `yaml:"title"`
-	Includes    string            `yaml:"includes"`
-	Description Localized[string] `yaml:"description"`
-	Singular    string            `yaml:"singular"`
-	Plural      string            `yaml:"plural"`
+	// The ID is the collection's key in collections.yaml
+	ID          string            `yaml:"-" json:"id"`
+	Title       Localized[string] `yaml:"title" json:"title"`
+	Includes    string            `yaml:"includes" json:"includes"`
+	Description Localized[string] `yaml:"description" json:"description"`
+	Singular    string            `yaml:"singular" json:"singular"`
+	Plural      string            `yaml:"plural" json:"plural"`
+	// Works lists IDs of works in the order they should appear in. Included works that are not listed come after, sorted by ID.
+	Works []string `yaml:"works,omitempty" json:"works"`
 }
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ortfodb "github.com/ortfo/db"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MediaOptimization reports what happened when producing the web-optimized variant of a media file.
+type MediaOptimization struct {
+	Source        string `json:"source"`
+	Variant       string `json:"variant"`
+	OriginalSize  int64  `json:"originalSize"`
+	OptimizedSize int64  `json:"optimizedSize"`
+	SavedBytes    int64  `json:"savedBytes"`
+	// Skipped is true when no variant was produced: optimization is disabled, the media is not an image that can be re-encoded,
+	// or the variant would not be smaller than the original.
+	Skipped bool `json:"skipped"`
+}
+
+// canOptimize returns true if the given content type can be re-encoded by OptimizeMedia.
+func canOptimize(contentType string) bool {
+	switch contentType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return true
+	default:
+		return false
+	}
+}
+
+// OptimizedVariantPath returns where the optimized variant of the media should be written:
+// right next to its copy in the media folder (e.g. media/work-1/media.optimized.jpeg for media/work-1/media.png).
+func OptimizedVariantPath(workID string, media ortfodb.Media, format string) string {
+	original := media.RelativeSource.RelativeToMediaRoot(ctx, workID).Absolute(ctx)
+	extension := filepath.Ext(original)
+	if format == "" {
+		format = strings.TrimPrefix(extension, ".")
+	}
+	return strings.TrimSuffix(original, extension) + ".optimized." + format
+}
+
+// OptimizeMedia produces a web-optimized variant of the given media of workID, according to the portfolio's settings.
+// Re-encoding the image also gets rid of all of its metadata (EXIF, including GPS coordinates).
+func (settings *Settings) OptimizeMedia(workID string, media ortfodb.Media) (MediaOptimization, error) {
+	config := settings.MediaOptimization
+	report := MediaOptimization{Source: string(media.RelativeSource)}
+	if !config.Enabled {
+		report.Skipped = true
+		return report, nil
+	}
+
+	source := media.RelativeSource.Absolute(ctx, workID)
+	contentType := media.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(source))
+	}
+	if !canOptimize(contentType) {
+		report.Skipped = true
+		return report, nil
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return report, fmt.Errorf("while opening %s: %w", source, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return report, fmt.Errorf("while getting size of %s: %w", source, err)
+	}
+	report.OriginalSize = stat.Size()
+
+	img, format, err := image.Decode(file)
+	if err != nil {
+		return report, fmt.Errorf("while decoding %s: %w", source, err)
+	}
+
+	if config.Format != "" {
+		format = config.Format
+	} else if format != "png" && format != "jpeg" {
+		format = "png"
+	}
+
+	img = fitInside(img, config.MaxWidth, config.MaxHeight)
+
+	var encoded bytes.Buffer
+	switch format {
+	case "jpeg":
+		quality := config.Quality
+		if quality <= 0 || quality > 100 {
+			quality = jpeg.DefaultQuality
+		}
+		err = jpeg.Encode(&encoded, flatten(img, color.White), &jpeg.Options{Quality: quality})
+	default:
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&encoded, img)
+	}
+	if err != nil {
+		return report, fmt.Errorf("while encoding %s to %s: %w", source, format, err)
+	}
+
+	variant := OptimizedVariantPath(workID, media, format)
+	if int64(encoded.Len()) >= report.OriginalSize {
+		// The original is already smaller: a variant would be useless. Remove the one left by a previous optimization, if any.
+		os.Remove(variant)
+		report.Skipped = true
+		return report, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(variant), 0755)
+	if err != nil {
+		return report, fmt.Errorf("couldn't create missing directories: %w", err)
+	}
+	err = os.WriteFile(variant, encoded.Bytes(), 0644)
+	if err != nil {
+		return report, fmt.Errorf("while writing %s: %w", variant, err)
+	}
+
+	report.Variant = variant
+	report.OptimizedSize = int64(encoded.Len())
+	report.SavedBytes = report.OriginalSize - report.OptimizedSize
+	LogToBrowser("Optimized %s into %s: saved %d bytes", source, variant, report.SavedBytes)
+	return report, nil
+}
+
+// fitInside scales img down so that it fits inside maxWidth×maxHeight, keeping its aspect ratio.
+// A zero maximum means no limit on that dimension.
+func fitInside(img image.Image, maxWidth int, maxHeight int) image.Image {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && height > maxHeight && float64(maxHeight)/float64(height) < scale {
+		scale = float64(maxHeight) / float64(height)
+	}
+	if scale == 1.0 {
+		return img
+	}
+
+	resized := image.NewRGBA(image.Rect(0, 0, int(float64(width)*scale), int(float64(height)*scale)))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, img.Bounds(), draw.Over, nil)
+	return resized
+}
+
+// flatten draws img on top of a solid background, since JPEG has no transparency.
+func flatten(img image.Image, background color.Color) image.Image {
+	flattened := image.NewRGBA(img.Bounds())
+	draw.Draw(flattened, flattened.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(flattened, flattened.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flattened
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/EdlinOrg/prominentcolor"
+	"github.com/gabriel-vasile/mimetype"
+	ortfodb "github.com/ortfo/db"
+	_ "golang.org/x/image/webp"
+)
+
+// PaletteCandidate is a color palette extracted from a media file,
+// along with the contrast ratio of each of its colors against the work's page background.
+type PaletteCandidate struct {
+	Palette  ortfodb.ColorPalette `json:"palette"`
+	Contrast PaletteContrast      `json:"contrast"`
+}
+
+// PaletteContrast holds WCAG contrast ratios of a palette's colors against a background. Unset colors have a ratio of 0.
+type PaletteContrast struct {
+	Background string  `json:"background"`
+	Primary    float64 `json:"primary"`
+	Secondary  float64 `json:"secondary"`
+	Tertiary   float64 `json:"tertiary"`
+}
+
+// How many frames are sampled from videos to extract colors from.
+const paletteVideoFrames = 4
+
+// Variations of the k-means extraction used to get different palette candidates from the same image.
+var paletteExtractionArguments = []int{
+	prominentcolor.ArgumentDefault,
+	prominentcolor.ArgumentNoCropping,
+	prominentcolor.ArgumentAverageMean,
+	prominentcolor.ArgumentNoCropping | prominentcolor.ArgumentAverageMean,
+}
+
+// ResolveMediaPath turns a path to a media file of the work with ID workID into an absolute path.
+// The path can be absolute, relative to the work's description (as media sources are), relative to the portfolio database directory,
+// or relative to the projects folder.
+func (settings *Settings) ResolveMediaPath(workID string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if workID != "" {
+		absolute := ortfodb.FilePathInsidePortfolioFolder(path).Absolute(ctx, workID)
+		if _, err := os.Stat(absolute); err == nil {
+			return absolute
+		}
+	}
+	if _, err := os.Stat(ConfigurationDirectory("portfolio-database", path)); err == nil {
+		return ConfigurationDirectory("portfolio-database", path)
+	}
+	return JoinPaths(settings.ProjectsFolder, path)
+}
+
+// ExtractPalettes extracts up to count candidate palettes from the media file at mediaPath (see ResolveMediaPath).
+// Images, SVGs and videos (by sampling a few frames) are supported.
+// Contrast ratios are computed against the page background of the work with ID workID, or white if it has none.
+func (settings *Settings) ExtractPalettes(workID string, mediaPath string, count int) ([]PaletteCandidate, error) {
+	background := "#ffffff"
+	if workID != "" {
+		db, err := settings.LoadDatabase()
+		if err != nil {
+			return nil, fmt.Errorf("while loading database: %w", err)
+		}
+		if work, ok := db[workID]; ok && work.Metadata.PageBackground != "" {
+			background = work.Metadata.PageBackground
+		}
+	}
+
+	images, err := decodeForPalette(settings.ResolveMediaPath(workID, mediaPath))
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]PaletteCandidate, 0)
+	seen := make(map[ortfodb.ColorPalette]bool)
+	for _, img := range images {
+		for _, arguments := range paletteExtractionArguments {
+			if count > 0 && len(candidates) >= count {
+				return candidates, nil
+			}
+			centroids, err := prominentcolor.KmeansWithAll(prominentcolor.DefaultK, img, arguments, prominentcolor.DefaultSize, prominentcolor.GetDefaultMasks())
+			if err != nil || len(centroids) < 3 {
+				continue
+			}
+			palette := ortfodb.ColorPalette{
+				Primary:   "#" + centroids[0].AsString(),
+				Secondary: "#" + centroids[1].AsString(),
+				Tertiary:  "#" + centroids[2].AsString(),
+			}
+			if seen[palette] {
+				continue
+			}
+			seen[palette] = true
+			candidates = append(candidates, PaletteCandidate{
+				Palette:  palette,
+				Contrast: paletteContrast(palette, background),
+			})
+		}
+	}
+	return candidates, nil
+}
+
+func paletteContrast(palette ortfodb.ColorPalette, background string) PaletteContrast {
+	ratio := func(color string) float64 {
+		if color == "" {
+			return 0
+		}
+		contrast, err := ContrastRatio(color, background)
+		if err != nil {
+			return 0
+		}
+		return contrast
+	}
+	return PaletteContrast{
+		Background: background,
+		Primary:    ratio(palette.Primary),
+		Secondary:  ratio(palette.Secondary),
+		Tertiary:   ratio(palette.Tertiary),
+	}
+}
+
+// decodeForPalette returns images to extract colors from for the given file:
+// the image itself, a rasterized version of SVGs, or a few frames of videos.
+func decodeForPalette(path string) ([]image.Image, error) {
+	contentType, err := mimetype.DetectFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("while detecting the type of %s: %w", path, err)
+	}
+
+	switch {
+	case contentType.Is("image/svg+xml"):
+		rasterized, err := runForImage("convert", path, "png:-")
+		if err != nil {
+			return nil, fmt.Errorf("while rasterizing %s: %w", path, err)
+		}
+		return []image.Image{rasterized}, nil
+	case strings.HasPrefix(contentType.String(), "video/"):
+		_, duration, _, err := ortfodb.AnalyzeVideo(path)
+		if err != nil {
+			return nil, fmt.Errorf("while getting duration of %s: %w", path, err)
+		}
+		frames := make([]image.Image, 0, paletteVideoFrames)
+		for i := 1; i <= paletteVideoFrames; i++ {
+			timestamp := float64(duration) * float64(i) / float64(paletteVideoFrames+1)
+			frame, err := runForImage("ffmpeg", "-loglevel", "error", "-ss", fmt.Sprintf("%.2f", timestamp), "-i", path, "-frames:v", "1", "-f", "image2pipe", "-vcodec", "png", "-")
+			if err != nil {
+				return nil, fmt.Errorf("while extracting frame at %.2fs of %s: %w", timestamp, path, err)
+			}
+			frames = append(frames, frame)
+		}
+		return frames, nil
+	default:
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		img, _, err := image.Decode(file)
+		if err != nil {
+			return nil, fmt.Errorf("while decoding %s: %w", path, err)
+		}
+		return []image.Image{img}, nil
+	}
+}
+
+// runForImage runs the given command and decodes its standard output as an image.
+func runForImage(command string, args ...string) (image.Image, error) {
+	var stdout, stderr bytes.Buffer
+	proc := exec.Command(command, args...)
+	proc.Stdout = &stdout
+	proc.Stderr = &stderr
+	err := proc.Run()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", command, err, stderr.String())
+	}
+	img, _, err := image.Decode(&stdout)
+	return img, err
+}
+
+// ApplyColors sets the colors of the work with ID workID and writes its description back.
+func (settings *Settings) ApplyColors(workID string, palette ortfodb.ColorPalette) error {
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return fmt.Errorf("while loading database: %w", err)
+	}
+	work, ok := db[workID]
+	if !ok {
+		return fmt.Errorf("no work with ID %q in the database", workID)
+	}
+
+	work.Metadata.Colors = palette
+	err = settings.writebackWork(&work, workID)
+	if err != nil {
+		return fmt.Errorf("while writing back description of %s: %w", workID, err)
+	}
+	db[workID] = work
+	ctx.WriteDatabase(db, ctx.Flags, ctx.OutputDatabaseFile, db.Partial())
+	return nil
+}
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MediaPrivacyLeak describes personal metadata found in a media file.
+type MediaPrivacyLeak struct {
+	WorkID string `json:"workID"`
+	// Absolute path to the media file, as expected by ScrubMedia.
+	Path          string   `json:"path"`
+	Source        string   `json:"source"`
+	GPS           bool     `json:"gps"`
+	SerialNumbers []string `json:"serialNumbers"`
+	Authors       []string `json:"authors"`
+	// Flagged is true when the work is public, meaning that the metadata will be published.
+	Flagged bool `json:"flagged"`
+}
+
+// exifMetadata is the privacy-sensitive subset of a file's EXIF metadata.
+type exifMetadata struct {
+	GPS           bool
+	SerialNumbers []string
+	Authors       []string
+}
+
+func (m exifMetadata) Empty() bool {
+	return !m.GPS && len(m.SerialNumbers) == 0 && len(m.Authors) == 0
+}
+
+const (
+	exifTagArtist          = 0x013B
+	exifTagCopyright       = 0x8298
+	exifTagExifIFD         = 0x8769
+	exifTagGPSIFD          = 0x8825
+	exifTagCameraOwnerName = 0xA430
+	exifTagBodySerial      = 0xA431
+	exifTagLensSerial      = 0xA435
+	exifTagGPSLatitude     = 0x0002
+	exifTagGPSLongitude    = 0x0004
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// ScanMediaPrivacy inspects every media file referenced in the database for GPS coordinates,
+// camera serial numbers and author information.
+func (settings *Settings) ScanMediaPrivacy() ([]MediaPrivacyLeak, error) {
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("while loading database: %w", err)
+	}
+
+	leaks := make([]MediaPrivacyLeak, 0)
+	for workID, work := range db {
+		scanned := make(map[string]bool)
+		for _, content := range work.Content {
+			for _, block := range content.Blocks {
+				if !block.Type.IsMedia() {
+					continue
+				}
+				path := block.RelativeSource.Absolute(ctx, workID)
+				if scanned[path] {
+					continue
+				}
+				scanned[path] = true
+
+				metadata, err := readPrivateMetadata(path)
+				if err != nil {
+					ErrorToBrowser("while scanning %s for metadata: %s", path, err)
+					continue
+				}
+				if metadata.Empty() {
+					continue
+				}
+				leaks = append(leaks, MediaPrivacyLeak{
+					WorkID:        workID,
+					Path:          path,
+					Source:        string(block.RelativeSource),
+					GPS:           metadata.GPS,
+					SerialNumbers: metadata.SerialNumbers,
+					Authors:       metadata.Authors,
+					Flagged:       !work.Metadata.Private,
+				})
+			}
+		}
+	}
+	return leaks, nil
+}
+
+// insideOrtfoFolder returns true if path is inside the .ortfo folder of a work of the projects folder.
+func (settings *Settings) insideOrtfoFolder(path string) bool {
+	relative, err := filepath.Rel(JoinPaths(settings.ProjectsFolder), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(relative, string(filepath.Separator))
+	return len(parts) >= 3 && parts[0] != ".." && parts[1] == ".ortfo"
+}
+
+// ScrubMedia rewrites the given media files without their privacy-sensitive metadata.
+// Image data is kept as-is: GPS, serial number and author tags are removed from EXIF data, other metadata segments (JPEG) or chunks (PNG) are removed.
+// Only media files inside a work's .ortfo folder can be scrubbed.
+func (settings *Settings) ScrubMedia(paths []string) error {
+	for _, path := range paths {
+		if !settings.insideOrtfoFolder(path) {
+			return fmt.Errorf("cannot scrub %s: it is not in the .ortfo folder of a work", path)
+		}
+	}
+
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("while reading %s: %w", path, err)
+		}
+
+		var scrubbed []byte
+		switch {
+		case isJPEG(raw):
+			scrubbed, err = scrubJPEG(raw)
+		case bytes.HasPrefix(raw, pngSignature):
+			scrubbed, err = scrubPNG(raw)
+		default:
+			return fmt.Errorf("cannot scrub %s: only JPEG and PNG files are supported", path)
+		}
+		if err != nil {
+			return fmt.Errorf("while scrubbing %s: %w", path, err)
+		}
+
+		// Write to a temporary file first so that a crash does not leave a half-written media file behind.
+		temporary := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".scrubbed")
+		err = os.WriteFile(temporary, scrubbed, 0644)
+		if err != nil {
+			return fmt.Errorf("while writing scrubbed version of %s: %w", path, err)
+		}
+		err = os.Rename(temporary, path)
+		if err != nil {
+			return fmt.Errorf("while replacing %s with its scrubbed version: %w", path, err)
+		}
+		LogToBrowser("Scrubbed metadata from %s", path)
+	}
+	return nil
+}
+
+func isJPEG(raw []byte) bool {
+	return len(raw) > 2 && raw[0] == 0xFF && raw[1] == 0xD8
+}
+
+// readPrivateMetadata returns privacy-sensitive metadata from the file at path.
+// Files that are not JPEG or PNG are reported as having no metadata, and are not read past their signature.
+// Of JPEG and PNG files, only the segments or chunks that can hold such metadata are read.
+func readPrivateMetadata(path string) (exifMetadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return exifMetadata{}, err
+	}
+	defer file.Close()
+
+	signature := make([]byte, len(pngSignature))
+	read, err := io.ReadFull(file, signature)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return exifMetadata{}, err
+	}
+	signature = signature[:read]
+
+	switch {
+	case isJPEG(signature):
+		raw, err := readJPEGMetadataSegments(file)
+		if err != nil {
+			return exifMetadata{}, err
+		}
+		return jpegMetadata(raw), nil
+	case bytes.Equal(signature, pngSignature):
+		raw, err := readPNGMetadataChunks(file)
+		if err != nil {
+			return exifMetadata{}, err
+		}
+		return pngMetadata(raw), nil
+	}
+	return exifMetadata{}, nil
+}
+
+// Metadata chunks larger than this are considered corrupted, instead of being read into memory.
+const maxMetadataSize = 16 << 20
+
+// readJPEGMetadataSegments reads the APP1 segments of a JPEG file, where EXIF data is, skipping the others.
+// It returns them as a JPEG file that has nothing else, for jpegMetadata.
+func readJPEGMetadataSegments(file io.ReadSeeker) ([]byte, error) {
+	if _, err := file.Seek(2, io.SeekStart); err != nil {
+		return nil, err
+	}
+	raw := []byte{0xFF, 0xD8}
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(file, header); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return raw, nil
+		} else if err != nil {
+			return nil, err
+		}
+		marker := header[1]
+		// Start of scan: everything after is image data
+		if header[0] != 0xFF || marker == 0xDA {
+			return raw, nil
+		}
+		length := int(binary.BigEndian.Uint16(header[2:4]))
+		if length < 2 {
+			return raw, nil
+		}
+		if marker != 0xE1 {
+			if _, err := file.Seek(int64(length-2), io.SeekCurrent); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		segment := make([]byte, 2+length)
+		copy(segment, header)
+		if _, err := io.ReadFull(file, segment[4:]); err != nil {
+			return raw, nil
+		}
+		raw = append(raw, segment...)
+	}
+}
+
+// readPNGMetadataChunks reads the eXIf, tEXt and iTXt chunks of a PNG file, skipping the others.
+// It returns them as a PNG file that has nothing else, for pngMetadata.
+func readPNGMetadataChunks(file io.ReadSeeker) ([]byte, error) {
+	raw := append([]byte{}, pngSignature...)
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(file, header); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return raw, nil
+		} else if err != nil {
+			return nil, err
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		switch string(header[4:8]) {
+		case "IEND":
+			return raw, nil
+		case "eXIf", "tEXt", "iTXt":
+			if length > maxMetadataSize {
+				return nil, fmt.Errorf("%s chunk of %d bytes is too large", header[4:8], length)
+			}
+			chunk := make([]byte, 8+length+4)
+			copy(chunk, header)
+			if _, err := io.ReadFull(file, chunk[8:]); err != nil {
+				return raw, nil
+			}
+			raw = append(raw, chunk...)
+		default:
+			if _, err := file.Seek(length+4, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// jpegSegments calls handle on every segment before the image data of a JPEG file.
+// handle receives the marker and the entire segment, including the marker and length bytes.
+// The returned offset is where the image data (start of scan) begins.
+func jpegSegments(raw []byte, handle func(marker byte, segment []byte)) (offset int, err error) {
+	offset = 2
+	for offset+4 <= len(raw) {
+		if raw[offset] != 0xFF {
+			return offset, fmt.Errorf("invalid JPEG marker at offset %d", offset)
+		}
+		marker := raw[offset+1]
+		// Start of scan: everything after is image data
+		if marker == 0xDA {
+			return offset, nil
+		}
+		length := int(binary.BigEndian.Uint16(raw[offset+2 : offset+4]))
+		if length < 2 || offset+2+length > len(raw) {
+			return offset, fmt.Errorf("truncated JPEG segment at offset %d", offset)
+		}
+		handle(marker, raw[offset:offset+2+length])
+		offset += 2 + length
+	}
+	return offset, nil
+}
+
+func jpegMetadata(raw []byte) (metadata exifMetadata) {
+	jpegSegments(raw, func(marker byte, segment []byte) {
+		if marker == 0xE1 && bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			metadata = parseTIFFMetadata(segment[10:])
+		}
+	})
+	return
+}
+
+// scrubJPEG removes privacy-sensitive tags from the EXIF segment, and removes XMP, APP13 (IPTC) and comment segments from a JPEG file.
+// The rest of the EXIF data is kept, most importantly the orientation, without which portrait photos would be displayed rotated.
+func scrubJPEG(raw []byte) ([]byte, error) {
+	scrubbed := bytes.NewBuffer(raw[:2:2])
+	imageData, err := jpegSegments(raw, func(marker byte, segment []byte) {
+		if marker == 0xE1 && bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			scrubbed.Write(segment[:10])
+			scrubbed.Write(scrubTIFFMetadata(segment[10:]))
+			return
+		}
+		if marker == 0xE1 || marker == 0xED || marker == 0xFE {
+			return
+		}
+		scrubbed.Write(segment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	scrubbed.Write(raw[imageData:])
+	return scrubbed.Bytes(), nil
+}
+
+// pngChunks calls handle on every chunk of a PNG file, with the chunk's type, data and entire bytes (including length and CRC).
+func pngChunks(raw []byte, handle func(chunkType string, data []byte, chunk []byte)) error {
+	offset := len(pngSignature)
+	for offset+8 <= len(raw) {
+		length := int(binary.BigEndian.Uint32(raw[offset : offset+4]))
+		chunkType := string(raw[offset+4 : offset+8])
+		end := offset + 8 + length + 4
+		if length < 0 || end > len(raw) {
+			return fmt.Errorf("truncated PNG chunk %q at offset %d", chunkType, offset)
+		}
+		handle(chunkType, raw[offset+8:offset+8+length], raw[offset:end])
+		offset = end
+	}
+	return nil
+}
+
+func pngMetadata(raw []byte) (metadata exifMetadata) {
+	pngChunks(raw, func(chunkType string, data []byte, _ []byte) {
+		switch chunkType {
+		case "eXIf":
+			exif := parseTIFFMetadata(data)
+			metadata.GPS = metadata.GPS || exif.GPS
+			metadata.SerialNumbers = append(metadata.SerialNumbers, exif.SerialNumbers...)
+			metadata.Authors = append(metadata.Authors, exif.Authors...)
+		case "tEXt", "iTXt":
+			keyword, value, _ := bytes.Cut(data, []byte{0})
+			if string(keyword) == "Author" || string(keyword) == "Copyright" {
+				// iTXt has compression flags and language tags between the keyword and the text
+				if chunkType == "iTXt" && len(value) >= 2 {
+					parts := bytes.SplitN(value[2:], []byte{0}, 3)
+					value = parts[len(parts)-1]
+				}
+				metadata.Authors = append(metadata.Authors, string(value))
+			}
+		}
+	})
+	return
+}
+
+// scrubPNG removes privacy-sensitive tags from the EXIF chunk, and removes textual chunks from a PNG file.
+func scrubPNG(raw []byte) ([]byte, error) {
+	scrubbed := bytes.NewBuffer(raw[:len(pngSignature):len(pngSignature)])
+	err := pngChunks(raw, func(chunkType string, data []byte, chunk []byte) {
+		switch chunkType {
+		case "eXIf":
+			// Same length, only the data and CRC change
+			data = scrubTIFFMetadata(data)
+			scrubbed.Write(chunk[:8])
+			scrubbed.Write(data)
+			binary.Write(scrubbed, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(chunkType), data...)))
+			return
+		case "tEXt", "zTXt", "iTXt", "tIME":
+			return
+		}
+		scrubbed.Write(chunk)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scrubbed.Bytes(), nil
+}
+
+// parseTIFFMetadata reads privacy-sensitive tags from EXIF data, which is stored as a TIFF header followed by IFDs.
+// Malformed data is ignored.
+func parseTIFFMetadata(tiff []byte) (metadata exifMetadata) {
+	if len(tiff) < 8 {
+		return
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return
+	}
+
+	entries := func(offset uint32) map[uint16][]byte {
+		result := make(map[uint16][]byte)
+		if int(offset)+2 > len(tiff) {
+			return result
+		}
+		count := int(order.Uint16(tiff[offset:]))
+		for i := 0; i < count; i++ {
+			start := int(offset) + 2 + i*12
+			if start+12 > len(tiff) {
+				break
+			}
+			entry := tiff[start : start+12]
+			tag := order.Uint16(entry[0:2])
+			size := int(order.Uint32(entry[4:8])) * tiffTypeSize(order.Uint16(entry[2:4]))
+			if size <= 4 {
+				result[tag] = entry[8 : 8+size]
+				continue
+			}
+			valueOffset := int(order.Uint32(entry[8:12]))
+			if valueOffset < 0 || valueOffset+size > len(tiff) {
+				continue
+			}
+			result[tag] = tiff[valueOffset : valueOffset+size]
+		}
+		return result
+	}
+	text := func(value []byte) string {
+		return strings.TrimSpace(strings.TrimRight(string(value), "\x00"))
+	}
+	pointer := func(value []byte) (uint32, bool) {
+		if len(value) != 4 {
+			return 0, false
+		}
+		return order.Uint32(value), true
+	}
+
+	ifd0 := entries(order.Uint32(tiff[4:8]))
+	for _, tag := range []uint16{exifTagArtist, exifTagCopyright} {
+		if value := text(ifd0[tag]); value != "" {
+			metadata.Authors = append(metadata.Authors, value)
+		}
+	}
+	if offset, ok := pointer(ifd0[exifTagExifIFD]); ok {
+		exifIFD := entries(offset)
+		if value := text(exifIFD[exifTagCameraOwnerName]); value != "" {
+			metadata.Authors = append(metadata.Authors, value)
+		}
+		for _, tag := range []uint16{exifTagBodySerial, exifTagLensSerial} {
+			if value := text(exifIFD[tag]); value != "" {
+				metadata.SerialNumbers = append(metadata.SerialNumbers, value)
+			}
+		}
+	}
+	if offset, ok := pointer(ifd0[exifTagGPSIFD]); ok {
+		gpsIFD := entries(offset)
+		_, hasLatitude := gpsIFD[exifTagGPSLatitude]
+		_, hasLongitude := gpsIFD[exifTagGPSLongitude]
+		metadata.GPS = hasLatitude || hasLongitude
+	}
+	return
+}
+
+// scrubTIFFMetadata returns a copy of EXIF data without the tags read by parseTIFFMetadata: the GPS IFD, artist, copyright,
+// camera owner and serial numbers. Entries are removed from their IFD and their values are zeroed out.
+// The data keeps the same length and every other tag stays where it was, so that offsets remain valid. Malformed data is returned as-is.
+func scrubTIFFMetadata(tiff []byte) []byte {
+	scrubbed := append([]byte{}, tiff...)
+	if len(scrubbed) < 8 {
+		return scrubbed
+	}
+	var order binary.ByteOrder
+	switch string(scrubbed[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return scrubbed
+	}
+
+	// entries returns the entries of the IFD at offset, and where the IFD's entries end. Malformed IFDs have no entries.
+	entries := func(offset uint32) ([][]byte, int) {
+		if int(offset)+2 > len(scrubbed) {
+			return nil, 0
+		}
+		start := int(offset) + 2
+		end := start + int(order.Uint16(scrubbed[offset:]))*12
+		if end+4 > len(scrubbed) {
+			return nil, 0
+		}
+		result := make([][]byte, 0)
+		for entry := start; entry < end; entry += 12 {
+			result = append(result, scrubbed[entry:entry+12])
+		}
+		return result, end
+	}
+	pointer := func(offset uint32, tag uint16) (uint32, bool) {
+		ifd, _ := entries(offset)
+		for _, entry := range ifd {
+			if order.Uint16(entry[0:2]) == tag && order.Uint32(entry[4:8])*uint32(tiffTypeSize(order.Uint16(entry[2:4]))) == 4 {
+				return order.Uint32(entry[8:12]), true
+			}
+		}
+		return 0, false
+	}
+	zero := func(data []byte) {
+		for i := range data {
+			data[i] = 0
+		}
+	}
+	// removeEntries rewrites the IFD at offset in place, without the entries for which remove returns true:
+	// kept entries, then the pointer to the next IFD, then zeros where the removed entries were.
+	removeEntries := func(offset uint32, remove func(tag uint16) bool) {
+		ifd, end := entries(offset)
+		if ifd == nil {
+			return
+		}
+		kept := make([]byte, 0, len(ifd)*12)
+		for _, entry := range ifd {
+			if !remove(order.Uint16(entry[0:2])) {
+				kept = append(kept, entry...)
+				continue
+			}
+			size := int(order.Uint32(entry[4:8])) * tiffTypeSize(order.Uint16(entry[2:4]))
+			valueOffset := int(order.Uint32(entry[8:12]))
+			if size > 4 && valueOffset >= 0 && valueOffset+size <= len(scrubbed) {
+				zero(scrubbed[valueOffset : valueOffset+size])
+			}
+		}
+		nextIFD := append([]byte{}, scrubbed[end:end+4]...)
+		start := int(offset) + 2
+		order.PutUint16(scrubbed[offset:], uint16(len(kept)/12))
+		copy(scrubbed[start:], kept)
+		copy(scrubbed[start+len(kept):], nextIFD)
+		zero(scrubbed[start+len(kept)+4 : end+4])
+	}
+
+	ifd0 := order.Uint32(scrubbed[4:8])
+	if exifIFD, ok := pointer(ifd0, exifTagExifIFD); ok {
+		removeEntries(exifIFD, func(tag uint16) bool {
+			return tag == exifTagCameraOwnerName || tag == exifTagBodySerial || tag == exifTagLensSerial
+		})
+	}
+	if gpsIFD, ok := pointer(ifd0, exifTagGPSIFD); ok {
+		removeEntries(gpsIFD, func(tag uint16) bool { return true })
+	}
+	removeEntries(ifd0, func(tag uint16) bool {
+		return tag == exifTagArtist || tag == exifTagCopyright || tag == exifTagGPSIFD
+	})
+	return scrubbed
+}
+
+// tiffTypeSize returns the size in bytes of a single value of the given TIFF field type.
+func tiffTypeSize(fieldType uint16) int {
+	switch fieldType {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+// Collection queries select works. Their grammar is:
+//
+//	query      = or
+//	or         = and { ("or" | "||") and }
+//	and        = unary { ("and" | "&&") unary }
+//	unary      = ("not" | "!") unary | "(" query ")" | predicate
+//	predicate  = "wip" | "private"
+//	           | ("tag" | "technology" | "tech" | "madewith") ":" value
+//	           | ("started" | "finished" | "date") ("=" | "<" | "<=" | ">" | ">=") date
+//	value      = word | "quoted string"
+//	date       = YYYY | YYYY-MM | YYYY-MM-DD
+//
+// Keywords are case-insensitive. Tags and technologies are matched through their aliases.
+// An empty query, such as the one of a collection whose query was never written, matches no works.
+
+// QueryError is a syntax error in a collection query. Position is the byte offset where the error occured.
+type QueryError struct {
+	Position int    `json:"position"`
+	Message  string `json:"message"`
+}
+
+func (e QueryError) Error() string {
+	return fmt.Sprintf("at position %d: %s", e.Position, e.Message)
+}
+
+type queryTokenKind int
+
+const (
+	tokenEOF queryTokenKind = iota
+	tokenWord
+	tokenString
+	tokenColon
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenNot
+	tokenAnd
+	tokenOr
+)
+
+type queryToken struct {
+	kind     queryTokenKind
+	value    string
+	position int
+}
+
+var patternQueryDate = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+
+func tokenizeQuery(query string) ([]queryToken, error) {
+	tokens := make([]queryToken, 0)
+	for i := 0; i < len(query); {
+		char := query[i]
+		switch {
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			i++
+		case char == '(':
+			tokens = append(tokens, queryToken{tokenLeftParen, "(", i})
+			i++
+		case char == ')':
+			tokens = append(tokens, queryToken{tokenRightParen, ")", i})
+			i++
+		case char == ':':
+			tokens = append(tokens, queryToken{tokenColon, ":", i})
+			i++
+		case char == '!':
+			tokens = append(tokens, queryToken{tokenNot, "!", i})
+			i++
+		case strings.HasPrefix(query[i:], "&&"):
+			tokens = append(tokens, queryToken{tokenAnd, "&&", i})
+			i += 2
+		case strings.HasPrefix(query[i:], "||"):
+			tokens = append(tokens, queryToken{tokenOr, "||", i})
+			i += 2
+		case char == '<' || char == '>' || char == '=':
+			operator := string(char)
+			if (char == '<' || char == '>') && i+1 < len(query) && query[i+1] == '=' {
+				operator += "="
+			}
+			tokens = append(tokens, queryToken{tokenOperator, operator, i})
+			i += len(operator)
+		case char == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end == -1 {
+				return nil, QueryError{i, "unterminated string"}
+			}
+			tokens = append(tokens, queryToken{tokenString, query[i+1 : i+1+end], i})
+			i += end + 2
+		default:
+			start := i
+			for i < len(query) && !strings.ContainsRune(" \t\n\r():!&|<>=\"", rune(query[i])) {
+				i++
+			}
+			if start == i {
+				return nil, QueryError{i, fmt.Sprintf("unexpected character %q", char)}
+			}
+			word := query[start:i]
+			kind := tokenWord
+			switch strings.ToLower(word) {
+			case "and":
+				kind = tokenAnd
+			case "or":
+				kind = tokenOr
+			case "not":
+				kind = tokenNot
+			}
+			tokens = append(tokens, queryToken{kind, word, start})
+		}
+	}
+	return append(tokens, queryToken{tokenEOF, "", len(query)}), nil
+}
+
+// queryEnvironment holds what predicates need to match works.
+type queryEnvironment struct {
+	tags         []Tag
+	technologies []Technology
+}
+
+// CollectionQuery is a parsed collection query.
+type CollectionQuery interface {
+	Matches(work ortfodb.Work, env queryEnvironment) bool
+}
+
+type queryNothing struct{}
+type queryOr struct{ left, right CollectionQuery }
+type queryAnd struct{ left, right CollectionQuery }
+type queryNot struct{ operand CollectionQuery }
+type queryFlag struct{ name string }
+type queryTag struct{ name string }
+type queryTechnology struct{ name string }
+type queryDate struct{ field, operator, date string }
+
+func (q queryNothing) Matches(work ortfodb.Work, env queryEnvironment) bool {
+	return false
+}
+
+func (q queryOr) Matches(work ortfodb.Work, env queryEnvironment) bool {
+	return q.left.Matches(work, env) || q.right.Matches(work, env)
+}
+
+func (q queryAnd) Matches(work ortfodb.Work, env queryEnvironment) bool {
+	return q.left.Matches(work, env) && q.right.Matches(work, env)
+}
+
+func (q queryNot) Matches(work ortfodb.Work, env queryEnvironment) bool {
+	return !q.operand.Matches(work, env)
+}
+
+func (q queryFlag) Matches(work ortfodb.Work, env queryEnvironment) bool {
+	if q.name == "wip" {
+		return work.Metadata.WIP
+	}
+	return work.Metadata.Private
+}
+
+func (q queryTag) Matches(work ortfodb.Work, env queryEnvironment) bool {
+	for _, name := range work.Metadata.Tags {
+		if strings.EqualFold(name, q.name) {
+			return true
+		}
+		for _, tag := range env.tags {
+			if tag.ReferredToBy(name) && tag.ReferredToBy(q.name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (q queryTechnology) Matches(work ortfodb.Work, env queryEnvironment) bool {
+	for _, name := range work.Metadata.MadeWith {
+		if strings.EqualFold(name, q.name) {
+			return true
+		}
+		for _, technology := range env.technologies {
+			if technology.ReferredToBy(name) && technology.ReferredToBy(q.name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (q queryDate) Matches(work ortfodb.Work, env queryEnvironment) bool {
+	var date string
+	switch q.field {
+	case "started":
+		date = work.Metadata.Started
+	case "finished":
+		date = work.Metadata.Finished
+	default:
+		date = workDate(work)
+	}
+	// Dates are compared at the precision of the query's date. YYYY-MM-DD dates can be compared as strings.
+	if len(date) < len(q.date) || strings.Contains(date[:len(q.date)], "?") {
+		return false
+	}
+	date = date[:len(q.date)]
+	switch q.operator {
+	case "<":
+		return date < q.date
+	case "<=":
+		return date <= q.date
+	case ">":
+		return date > q.date
+	case ">=":
+		return date >= q.date
+	default:
+		return date == q.date
+	}
+}
+
+// workDate returns the date that best represents when a work was made, in the same way ortfodb does.
+func workDate(work ortfodb.Work) string {
+	if created, ok := work.Metadata.AdditionalMetadata["created"].(string); ok {
+		return created
+	}
+	if work.Metadata.Finished != "" {
+		return work.Metadata.Finished
+	}
+	return work.Metadata.Started
+}
+
+type queryParser struct {
+	tokens  []queryToken
+	current int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.current]
+}
+
+func (p *queryParser) next() queryToken {
+	token := p.tokens[p.current]
+	if token.kind != tokenEOF {
+		p.current++
+	}
+	return token
+}
+
+func (p *queryParser) or() (CollectionQuery, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = queryOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) and() (CollectionQuery, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = queryAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) unary() (CollectionQuery, error) {
+	token := p.next()
+	switch token.kind {
+	case tokenNot:
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return queryNot{operand}, nil
+	case tokenLeftParen:
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRightParen {
+			return nil, QueryError{closing.position, fmt.Sprintf("expected ) to close ( at position %d", token.position)}
+		}
+		return inner, nil
+	case tokenWord:
+		return p.predicate(token)
+	case tokenEOF:
+		return nil, QueryError{token.position, "unexpected end of query"}
+	default:
+		return nil, QueryError{token.position, fmt.Sprintf("unexpected %q", token.value)}
+	}
+}
+
+func (p *queryParser) predicate(keyword queryToken) (CollectionQuery, error) {
+	switch name := strings.ToLower(keyword.value); name {
+	case "wip", "private":
+		return queryFlag{name}, nil
+	case "tag", "technology", "tech", "madewith":
+		if colon := p.next(); colon.kind != tokenColon {
+			return nil, QueryError{colon.position, fmt.Sprintf("expected : after %s", keyword.value)}
+		}
+		value := p.next()
+		if value.kind != tokenWord && value.kind != tokenString {
+			return nil, QueryError{value.position, fmt.Sprintf("expected a name after %s:", keyword.value)}
+		}
+		if name == "tag" {
+			return queryTag{value.value}, nil
+		}
+		return queryTechnology{value.value}, nil
+	case "started", "finished", "date":
+		operator := p.next()
+		if operator.kind != tokenOperator {
+			return nil, QueryError{operator.position, fmt.Sprintf("expected one of =, <, <=, >, >= after %s", keyword.value)}
+		}
+		date := p.next()
+		if date.kind != tokenWord || !patternQueryDate.MatchString(date.value) {
+			return nil, QueryError{date.position, "expected a date of the form YYYY, YYYY-MM or YYYY-MM-DD"}
+		}
+		return queryDate{name, operator.value, date.value}, nil
+	default:
+		return nil, QueryError{keyword.position, fmt.Sprintf("unknown predicate %q", keyword.value)}
+	}
+}
+
+// ParseCollectionQuery parses a collection query. Errors are of type QueryError.
+func ParseCollectionQuery(query string) (CollectionQuery, error) {
+	if strings.TrimSpace(query) == "" {
+		return queryNothing{}, nil
+	}
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	parser := queryParser{tokens: tokens}
+	parsed, err := parser.or()
+	if err != nil {
+		return nil, err
+	}
+	if extra := parser.peek(); extra.kind != tokenEOF {
+		return nil, QueryError{extra.position, fmt.Sprintf("unexpected %q", extra.value)}
+	}
+	return parsed, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+func TestEmptyCollectionQueryMatchesNothing(t *testing.T) {
+	db := ortfodb.Database{
+		"wip-work":      {ID: "wip-work", Metadata: ortfodb.WorkMetadata{WIP: true}},
+		"finished-work": {ID: "finished-work"},
+	}
+	for _, query := range []string{"", "   ", "\n\t"} {
+		parsed, err := ParseCollectionQuery(query)
+		if err != nil {
+			t.Fatalf("parsing %q: %s", query, err)
+		}
+		if workIDs := selectWorks(db, parsed, queryEnvironment{}, []string{"wip-work"}); len(workIDs) != 0 {
+			t.Errorf("query %q selected %v, expected no works", query, workIDs)
+		}
+	}
+}
+
+func TestCollectionQuery(t *testing.T) {
+	db := ortfodb.Database{
+		"wip-work":      {ID: "wip-work", Metadata: ortfodb.WorkMetadata{WIP: true, Started: "2021-03"}},
+		"finished-work": {ID: "finished-work", Metadata: ortfodb.WorkMetadata{Started: "2019"}},
+	}
+	for query, expected := range map[string][]string{
+		"wip":                         {"wip-work"},
+		"not wip":                     {"finished-work"},
+		"wip or started < 2020":       {"finished-work", "wip-work"},
+		"wip and started >= 2022":     {},
+		"!(wip) || started = 2021-03": {"finished-work", "wip-work"},
+	} {
+		parsed, err := ParseCollectionQuery(query)
+		if err != nil {
+			t.Fatalf("parsing %q: %s", query, err)
+		}
+		workIDs := selectWorks(db, parsed, queryEnvironment{}, nil)
+		if len(workIDs) != len(expected) {
+			t.Errorf("query %q selected %v, expected %v", query, workIDs, expected)
+			continue
+		}
+		for i := range expected {
+			if workIDs[i] != expected[i] {
+				t.Errorf("query %q selected %v, expected %v", query, workIDs, expected)
+				break
+			}
+		}
+	}
+}
+
+func TestCollectionQuerySyntaxError(t *testing.T) {
+	_, err := ParseCollectionQuery("wip and")
+	if _, ok := err.(QueryError); !ok {
+		t.Errorf("expected a QueryError, got %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+var (
+	// [![alt](image)](link)
+	patternLinkedMarkdownImage = regexp.MustCompile(`\[!\[[^\]]*\]\(\s*<?([^)\s>]+)>?[^)]*\)\]\([^)]*\)`)
+	// ![alt](image "title")
+	patternMarkdownImage = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(\s+"[^"]*")?\s*\)`)
+	// <img src="image">, possibly wrapped in a link
+	patternHTMLImage = regexp.MustCompile(`(?i)(<a\s[^>]*>\s*)?<img\s[^>]*src=["']([^"']+)["'][^>]*>(\s*</a>)?`)
+	// The src attribute of an <img> tag, with what comes before it in the tag
+	patternHTMLImageSource = regexp.MustCompile(`(?i)(<img\s[^>]*\bsrc=)(?:"([^"]+)"|'([^']+)')`)
+	patternFenceMarker     = regexp.MustCompile("^\\s*(```|~~~)")
+	patternATXHeading      = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	// Underline of a setext-style heading
+	patternSetextUnderline = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+)
+
+// badgeHosts are hosts that serve status badges.
+var badgeHosts = []string{
+	"shields.io", "badgen.net", "badge.fury.io", "travis-ci.org", "travis-ci.com", "circleci.com",
+	"codecov.io", "coveralls.io", "goreportcard.com", "pkg.go.dev", "godoc.org", "app.netlify.com",
+	"readthedocs.org", "deepscan.io", "codeclimate.com", "snyk.io", "bestpractices.coreinfrastructure.org",
+}
+
+// isBadge returns true if the image at source looks like a status badge.
+func isBadge(source string) bool {
+	parsed, err := url.Parse(source)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	for _, host := range badgeHosts {
+		if parsed.Host == host || strings.HasSuffix(parsed.Host, "."+host) {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(parsed.Path), "badge")
+}
+
+// stripBadges removes status badges from README contents, along with the lines that only contained badges.
+func stripBadges(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		stripped := patternLinkedMarkdownImage.ReplaceAllStringFunc(line, func(image string) string {
+			if isBadge(patternLinkedMarkdownImage.FindStringSubmatch(image)[1]) {
+				return ""
+			}
+			return image
+		})
+		stripped = patternMarkdownImage.ReplaceAllStringFunc(stripped, func(image string) string {
+			if isBadge(patternMarkdownImage.FindStringSubmatch(image)[2]) {
+				return ""
+			}
+			return image
+		})
+		stripped = patternHTMLImage.ReplaceAllStringFunc(stripped, func(image string) string {
+			if isBadge(patternHTMLImage.FindStringSubmatch(image)[2]) {
+				return ""
+			}
+			return image
+		})
+		if strings.TrimSpace(stripped) == "" && strings.TrimSpace(line) != "" {
+			continue
+		}
+		result = append(result, stripped)
+	}
+	return strings.Join(result, "\n")
+}
+
+// normalizeReadmeHeadings makes the first heading of the README a level-1 heading, so that it becomes the work's title,
+// and turns other level-1 headings into level-2 headings, since descriptions have only one title.
+// Setext-style headings (underlined with = or -) are turned into ATX-style headings (starting with #).
+func normalizeReadmeHeadings(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	result := make([]string, 0, len(lines))
+	inCodeBlock := false
+	seenTitle := false
+	heading := func(level int, text string) string {
+		if !seenTitle {
+			seenTitle = true
+			return "# " + text
+		}
+		if level == 1 {
+			level = 2
+		}
+		return strings.Repeat("#", level) + " " + text
+	}
+	for i, line := range lines {
+		if patternFenceMarker.MatchString(line) {
+			inCodeBlock = !inCodeBlock
+		}
+		if inCodeBlock || patternFenceMarker.MatchString(line) {
+			result = append(result, line)
+			continue
+		}
+		if match := patternATXHeading.FindStringSubmatch(line); match != nil {
+			result = append(result, heading(len(match[1]), match[2]))
+			continue
+		}
+		if i > 0 && len(result) > 0 && strings.TrimSpace(lines[i-1]) != "" && patternSetextUnderline.MatchString(line) && !strings.HasPrefix(result[len(result)-1], "#") {
+			level := 1
+			if strings.Contains(line, "-") {
+				level = 2
+			}
+			result[len(result)-1] = heading(level, strings.TrimSpace(lines[i-1]))
+			continue
+		}
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+// bringReadmeImages copies local images referenced by the README, either in markdown or with <img> tags, into the work's media folder,
+// and points the README to the copies. Paths are resolved relative to the README's directory. Remote images are left as is.
+func (settings *Settings) bringReadmeImages(markdown string, readmeDirectory string, workID string) (string, error) {
+	var err error
+	// bring returns the path to the copy of the image at source, or the empty string if it should be left as is.
+	bring := func(source string) string {
+		if err != nil || strings.Contains(source, "://") || strings.HasPrefix(source, "//") || strings.HasPrefix(source, "data:") {
+			return ""
+		}
+		source, _ = url.PathUnescape(strings.SplitN(source, "#", 2)[0])
+		var absolute string
+		if filepath.IsAbs(source) || strings.HasPrefix(source, "/") {
+			// Absolute paths in READMEs are relative to the repository root
+			absolute = filepath.Join(JoinPaths(settings.ProjectsFolder, workID), filepath.FromSlash(source))
+		} else {
+			absolute = filepath.Join(readmeDirectory, filepath.FromSlash(source))
+		}
+		if _, statErr := os.Stat(absolute); statErr != nil {
+			LogToBrowser("README image %s does not exist, leaving it as is", absolute)
+			return ""
+		}
+		var copied string
+		copied, err = settings.BringOutsideMedia(absolute, workID)
+		return copied
+	}
+
+	markdown = patternMarkdownImage.ReplaceAllStringFunc(markdown, func(image string) string {
+		groups := patternMarkdownImage.FindStringSubmatch(image)
+		alt, source, title := groups[1], groups[2], groups[3]
+		copied := bring(source)
+		if copied == "" {
+			return image
+		}
+		return fmt.Sprintf("![%s](%s%s)", alt, copied, title)
+	})
+	markdown = patternHTMLImageSource.ReplaceAllStringFunc(markdown, func(attribute string) string {
+		groups := patternHTMLImageSource.FindStringSubmatch(attribute)
+		source := groups[2] + groups[3]
+		copied := bring(source)
+		if copied == "" {
+			return attribute
+		}
+		return fmt.Sprintf(`%s"%s"`, groups[1], copied)
+	})
+	return markdown, err
+}
+
+// ImportFromReadme creates the content in lang of the work with ID workID from its project's README:
+// the first heading becomes the title, local images are copied into the work's media and badges are removed.
+// The work is created if it does not have a description yet.
+func (settings *Settings) ImportFromReadme(workID string, lang string) error {
+	projectFolder := JoinPaths(settings.ProjectsFolder, workID)
+	readmePath := findReadme(projectFolder)
+	if readmePath == "" {
+		return fmt.Errorf("%s has no README", projectFolder)
+	}
+	raw, err := os.ReadFile(readmePath)
+	if err != nil {
+		return fmt.Errorf("while reading %s: %w", readmePath, err)
+	}
+
+	// Check that the content can be imported before copying images, so that a refused import leaves nothing behind.
+	work, err := settings.workToImportInto(workID, lang)
+	if err != nil {
+		return err
+	}
+
+	markdown := normalizeReadmeHeadings(stripBadges(strings.ReplaceAll(string(raw), "\r\n", "\n")))
+	markdown, err = settings.bringReadmeImages(markdown, filepath.Dir(readmePath), workID)
+	if err != nil {
+		return fmt.Errorf("while copying images of %s: %w", readmePath, err)
+	}
+
+	return settings.importMarkdown(work, lang, markdown)
+}
+
+// workToImportInto returns the work with ID workID, or a new work if it does not have a description yet.
+// Works that already have content in lang cannot be imported into.
+func (settings *Settings) workToImportInto(workID string, lang string) (ortfodb.Work, error) {
+	projectFolder := JoinPaths(settings.ProjectsFolder, workID)
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return ortfodb.Work{}, fmt.Errorf("while loading database: %w", err)
+	}
+	work, exists := db[workID]
+	if !exists {
+		paths, _, _ := projectFiles(projectFolder)
+		started, _ := guessProjectDates(projectFolder, paths)
+		work = ortfodb.Work{
+			ID: workID,
+			Metadata: ortfodb.WorkMetadata{
+				Started:  started,
+				MadeWith: make([]string, 0),
+				Tags:     make([]string, 0),
+			},
+		}
+	}
+	if work.Content == nil {
+		work.Content = make(ortfodb.LocalizableContent)
+	}
+	if existing, ok := work.Content[lang]; ok && len(existing.Blocks) > 0 {
+		return work, fmt.Errorf("work %s already has content in %s", workID, lang)
+	}
+	return work, nil
+}
+
+// importMarkdown parses markdown as the lang content of work (see workToImportInto), and writes its description back.
+func (settings *Settings) importMarkdown(work ortfodb.Work, lang string, markdown string) error {
+	title, blocks, footnotes, _ := ctx.ParseSingleLanguageDescription(markdown)
+	layout, err := ortfodb.ResolveLayout(work.Metadata, lang, blocks)
+	if err != nil {
+		return fmt.Errorf("while laying out blocks: %w", err)
+	}
+	work.Content[lang] = ortfodb.LocalizedContent{
+		Layout:    layout,
+		Blocks:    blocks,
+		Title:     title,
+		Footnotes: footnotes,
+	}
+	return Writeback(*settings, work, work.ID)
+}
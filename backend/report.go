@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReportMedia is a media file listed in a PortfolioReport. Size is in bytes.
+type ReportMedia struct {
+	WorkID string `json:"workID"`
+	Source string `json:"source"`
+	Size   int    `json:"size"`
+}
+
+// PortfolioReport summarizes the state of the portfolio.
+// Works are counted per year of the date that best represents them (see workDate), "unknown" holds works without a date.
+// LanguageCoverage maps each portfolio language to the number of works that have content in it.
+// OutdatedWorks lists works whose description.md changed since the database was built.
+type PortfolioReport struct {
+	GeneratedAt         string         `json:"generatedAt"`
+	Works               int            `json:"works"`
+	WorksPerYear        map[string]int `json:"worksPerYear"`
+	WIP                 int            `json:"wip"`
+	Private             int            `json:"private"`
+	WithoutThumbnail    []string       `json:"withoutThumbnail"`
+	AverageMediaPerWork float64        `json:"averageMediaPerWork"`
+	LanguageCoverage    map[string]int `json:"languageCoverage"`
+	LargestMedia        []ReportMedia  `json:"largestMedia"`
+	OutdatedWorks       []string       `json:"outdatedWorks"`
+}
+
+// ReportFormats are the formats PortfolioReport can be exported to.
+var ReportFormats = [...]string{"markdown", "json"}
+
+// reportLargestMediaCount is the number of media files listed in PortfolioReport.LargestMedia.
+const reportLargestMediaCount = 10
+
+// PortfolioReport computes a report on the whole portfolio.
+func (settings *Settings) PortfolioReport() (PortfolioReport, error) {
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return PortfolioReport{}, fmt.Errorf("while loading database: %w", err)
+	}
+
+	report := PortfolioReport{
+		GeneratedAt:      time.Now().Format(time.RFC3339),
+		Works:            len(db),
+		WorksPerYear:     make(map[string]int),
+		WithoutThumbnail: make([]string, 0),
+		LanguageCoverage: make(map[string]int),
+		LargestMedia:     make([]ReportMedia, 0),
+		OutdatedWorks:    make([]string, 0),
+	}
+	for _, lang := range settings.PortfolioLanguages {
+		report.LanguageCoverage[lang] = 0
+	}
+
+	mediaCount := 0
+	for workID, work := range db {
+		year := "unknown"
+		if date := workDate(work); len(date) >= 4 && !strings.Contains(date[:4], "?") {
+			year = date[:4]
+		}
+		report.WorksPerYear[year]++
+		if work.Metadata.WIP {
+			report.WIP++
+		}
+		if work.Metadata.Private {
+			report.Private++
+		}
+
+		hasThumbnail := false
+		media := make(map[string]ReportMedia)
+		for lang, content := range work.Content {
+			if _, ok := report.LanguageCoverage[lang]; ok || lang == "default" {
+				report.LanguageCoverage[lang]++
+			}
+			for _, block := range content.Blocks {
+				if !block.Type.IsMedia() {
+					continue
+				}
+				hasThumbnail = hasThumbnail || len(block.Thumbnails) > 0
+				media[string(block.RelativeSource)] = ReportMedia{WorkID: workID, Source: string(block.RelativeSource), Size: block.Size}
+			}
+		}
+		if !hasThumbnail {
+			report.WithoutThumbnail = append(report.WithoutThumbnail, workID)
+		}
+		mediaCount += len(media)
+		for _, item := range media {
+			report.LargestMedia = append(report.LargestMedia, item)
+		}
+	}
+
+	staleness, err := settings.DatabaseStaleness(db)
+	if err != nil {
+		return report, fmt.Errorf("while comparing descriptions with the database: %w", err)
+	}
+	report.OutdatedWorks = staleness.Outdated
+
+	if len(db) > 0 {
+		report.AverageMediaPerWork = float64(mediaCount) / float64(len(db))
+	}
+	sort.Slice(report.LargestMedia, func(i, j int) bool {
+		return report.LargestMedia[i].Size > report.LargestMedia[j].Size
+	})
+	if len(report.LargestMedia) > reportLargestMediaCount {
+		report.LargestMedia = report.LargestMedia[:reportLargestMediaCount]
+	}
+	sort.Strings(report.WithoutThumbnail)
+	return report, nil
+}
+
+// humanSize formats a size in bytes with a binary unit.
+func humanSize(bytes int) string {
+	size := float64(bytes)
+	for _, unit := range []string{"B", "KiB", "MiB", "GiB"} {
+		if size < 1024 || unit == "GiB" {
+			if unit == "B" {
+				return fmt.Sprintf("%d %s", bytes, unit)
+			}
+			return fmt.Sprintf("%.1f %s", size, unit)
+		}
+		size /= 1024
+	}
+	return ""
+}
+
+// Markdown renders the report as a Markdown document.
+func (report PortfolioReport) Markdown() string {
+	var b strings.Builder
+	list := func(items []string) {
+		if len(items) == 0 {
+			b.WriteString("None.\n\n")
+			return
+		}
+		for _, item := range items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "# Portfolio report\n\nGenerated at %s.\n\n", report.GeneratedAt)
+	fmt.Fprintf(&b, "- %d works\n- %d works in progress\n- %d private works\n- %.1f media per work on average\n\n", report.Works, report.WIP, report.Private, report.AverageMediaPerWork)
+
+	b.WriteString("## Works per year\n\n| Year | Works |\n| --- | --- |\n")
+	years := make([]string, 0, len(report.WorksPerYear))
+	for year := range report.WorksPerYear {
+		years = append(years, year)
+	}
+	sort.Strings(years)
+	for _, year := range years {
+		fmt.Fprintf(&b, "| %s | %d |\n", year, report.WorksPerYear[year])
+	}
+
+	b.WriteString("\n## Languages coverage\n\n| Language | Works |\n| --- | --- |\n")
+	languages := make([]string, 0, len(report.LanguageCoverage))
+	for lang := range report.LanguageCoverage {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	for _, lang := range languages {
+		fmt.Fprintf(&b, "| %s | %d/%d |\n", lang, report.LanguageCoverage[lang], report.Works)
+	}
+
+	b.WriteString("\n## Largest media files\n\n")
+	if len(report.LargestMedia) == 0 {
+		b.WriteString("None.\n\n")
+	} else {
+		b.WriteString("| Work | File | Size |\n| --- | --- | --- |\n")
+		for _, media := range report.LargestMedia {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", media.WorkID, media.Source, humanSize(media.Size))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Works without thumbnails\n\n")
+	list(report.WithoutThumbnail)
+	b.WriteString("## Works not rebuilt since their description changed\n\n")
+	list(report.OutdatedWorks)
+	return strings.TrimSpace(b.String()) + "\n"
+}
+
+// Export renders the report in the given format, one of ReportFormats.
+func (report PortfolioReport) Export(format string) (string, error) {
+	switch format {
+	case "markdown":
+		return report.Markdown(), nil
+	case "json":
+		exported, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("while converting to JSON: %w", err)
+		}
+		return string(exported) + "\n", nil
+	}
+	return "", fmt.Errorf("unknown report format %q, valid formats are %v", format, ReportFormats)
+}
+
+// ExportPortfolioReport computes the portfolio report and writes it in the given format to outputPath, or to the standard output if outputPath is empty.
+func (settings *Settings) ExportPortfolioReport(format string, outputPath string) error {
+	report, err := settings.PortfolioReport()
+	if err != nil {
+		return err
+	}
+	exported, err := report.Export(format)
+	if err != nil {
+		return err
+	}
+	if outputPath == "" {
+		_, err = os.Stdout.WriteString(exported)
+		return err
+	}
+	return os.WriteFile(outputPath, []byte(exported), 0644)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ortfodb "github.com/ortfo/db"
+	"gopkg.in/yaml.v2"
+)
+
+// MetadataFieldType is the type of the values of a custom metadata field.
+type MetadataFieldType string
+
+const (
+	MetadataString MetadataFieldType = "string"
+	MetadataNumber MetadataFieldType = "number"
+	// Dates are of the form YYYY, YYYY-MM or YYYY-MM-DD, like started and finished.
+	MetadataDate MetadataFieldType = "date"
+	MetadataURL  MetadataFieldType = "url"
+	// Enums take one of the field's Values.
+	MetadataEnum  MetadataFieldType = "enum"
+	MetadataColor MetadataFieldType = "color"
+	// Localized fields map languages to strings.
+	MetadataLocalized MetadataFieldType = "localized"
+)
+
+var MetadataFieldTypes = [...]MetadataFieldType{MetadataString, MetadataNumber, MetadataDate, MetadataURL, MetadataEnum, MetadataColor, MetadataLocalized}
+
+// MetadataFieldSchema declares a custom field of works' additional metadata.
+// Values are the possible values of enum fields. Works that do not set the field are validated as if they had its Default value.
+type MetadataFieldSchema struct {
+	Name     string            `yaml:"name" json:"name"`
+	Type     MetadataFieldType `yaml:"type" json:"type"`
+	Required bool              `yaml:"required,omitempty" json:"required"`
+	Default  interface{}       `yaml:"default,omitempty" json:"default"`
+	Values   []string          `yaml:"values,omitempty" json:"values"`
+}
+
+// key returns the key of the field in WorkMetadata.AdditionalMetadata: ortfodb replaces spaces in metadata keys with underscores.
+func (field MetadataFieldSchema) key() string {
+	return strings.ReplaceAll(field.Name, " ", "_")
+}
+
+// LoadMetadataSchema reads the custom metadata fields declared in metadata-schema.yaml.
+// A missing schema file declares no fields.
+func LoadMetadataSchema() ([]MetadataFieldSchema, error) {
+	schema := make([]MetadataFieldSchema, 0)
+	raw, err := os.ReadFile(ConfigurationDirectory("metadata-schema.yaml"))
+	if os.IsNotExist(err) {
+		return schema, nil
+	} else if err != nil {
+		return schema, fmt.Errorf("while reading metadata schema: %w", err)
+	}
+	err = yaml.Unmarshal(raw, &schema)
+	if err != nil {
+		return schema, fmt.Errorf("while parsing metadata schema: %w", err)
+	}
+	return schema, ValidateMetadataSchema(schema)
+}
+
+// ValidateMetadataSchema checks that fields have a unique name and a known type, and that enums have values.
+func ValidateMetadataSchema(schema []MetadataFieldSchema) error {
+	seen := make(map[string]bool)
+	for _, field := range schema {
+		if strings.TrimSpace(field.Name) == "" {
+			return fmt.Errorf("a metadata field has no name")
+		}
+		if seen[field.key()] {
+			return fmt.Errorf("metadata field %q is declared more than once", field.Name)
+		}
+		seen[field.key()] = true
+
+		known := false
+		for _, fieldType := range MetadataFieldTypes {
+			known = known || field.Type == fieldType
+		}
+		if !known {
+			return fmt.Errorf("metadata field %q has unknown type %q, valid types are %v", field.Name, field.Type, MetadataFieldTypes)
+		}
+		if field.Type == MetadataEnum && len(field.Values) == 0 {
+			return fmt.Errorf("metadata field %q is an enum but declares no values", field.Name)
+		}
+		if field.Default != nil {
+			if problem := field.check(field.Default, nil); problem != "" {
+				return fmt.Errorf("default value of metadata field %q is invalid: %s", field.Name, problem)
+			}
+		}
+	}
+	return nil
+}
+
+// check returns what is wrong with value for this field, or the empty string if it is valid.
+// languages are the languages localized values should have.
+func (field MetadataFieldSchema) check(value interface{}, languages []string) string {
+	switch field.Type {
+	case MetadataNumber:
+		switch value.(type) {
+		case int, int64, uint64, float64:
+			return ""
+		}
+		return fmt.Sprintf("%v is not a number", value)
+	case MetadataLocalized:
+		localized, ok := value.(map[string]interface{})
+		if !ok {
+			if generic, isGeneric := value.(map[interface{}]interface{}); isGeneric {
+				localized = make(map[string]interface{}, len(generic))
+				for lang, text := range generic {
+					localized[fmt.Sprint(lang)] = text
+				}
+				ok = true
+			}
+		}
+		if !ok {
+			return "should map languages to text"
+		}
+		for lang, text := range localized {
+			if _, isString := text.(string); !isString {
+				return fmt.Sprintf("value in %s is not text", lang)
+			}
+		}
+		for _, lang := range languages {
+			if _, ok := localized[lang]; !ok {
+				if _, hasDefault := localized["default"]; !hasDefault {
+					return fmt.Sprintf("has no value in %s", lang)
+				}
+			}
+		}
+		return ""
+	}
+
+	if _, ok := value.(time.Time); ok && field.Type == MetadataDate {
+		return ""
+	}
+	text, ok := value.(string)
+	if !ok {
+		return fmt.Sprintf("%v is not text", value)
+	}
+	switch field.Type {
+	case MetadataDate:
+		if !patternWorkDate.MatchString(text) {
+			return fmt.Sprintf("%q is not a date of the form YYYY-MM-DD", text)
+		}
+	case MetadataURL:
+		return validateURL(text)
+	case MetadataEnum:
+		if !containsString(field.Values, text) {
+			return fmt.Sprintf("%q is not one of %s", text, strings.Join(field.Values, ", "))
+		}
+	case MetadataColor:
+		if _, _, _, err := parseHexColor(text); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+// ValidateWorkMetadata checks the additional metadata of work against schema.
+func ValidateWorkMetadata(work ortfodb.Work, schema []MetadataFieldSchema, languages []string) []LintIssue {
+	issues := make([]LintIssue, 0)
+	for _, field := range schema {
+		value, ok := work.Metadata.AdditionalMetadata[field.key()]
+		if !ok || value == nil {
+			value = field.Default
+		}
+		if value == nil {
+			if field.Required {
+				issues = append(issues, LintIssue{Severity: LintError, Rule: "metadata-schema", WorkID: work.ID, Message: fmt.Sprintf("Required metadata %q is missing", field.Name)})
+			}
+			continue
+		}
+		if problem := field.check(value, languages); problem != "" {
+			issues = append(issues, LintIssue{Severity: LintError, Rule: "metadata-schema", WorkID: work.ID, Message: fmt.Sprintf("Metadata %q: %s", field.Name, problem)})
+		}
+	}
+	return issues
+}
+
+var metadataIssues = make([]LintIssue, 0)
+var metadataIssuesLock sync.Mutex
+
+// MetadataIssues returns the issues found by the metadata schema validation done after the last rebuild, so that the UI can poll them.
+func MetadataIssues() []LintIssue {
+	metadataIssuesLock.Lock()
+	defer metadataIssuesLock.Unlock()
+	return metadataIssues
+}
+
+// validateMetadataAfterRebuild validates every work of the freshly built database db against the metadata schema.
+// The issues are returned, and kept for MetadataIssues.
+func (settings *Settings) validateMetadataAfterRebuild(db ortfodb.Database) []LintIssue {
+	issues := make([]LintIssue, 0)
+	schema, err := LoadMetadataSchema()
+	if err != nil {
+		issues = append(issues, LintIssue{Severity: LintError, Rule: "metadata-schema", Message: err.Error()})
+	} else {
+		workIDs := make([]string, 0, len(db))
+		for workID := range db {
+			workIDs = append(workIDs, workID)
+		}
+		sort.Strings(workIDs)
+		for _, workID := range workIDs {
+			issues = append(issues, ValidateWorkMetadata(db[workID], schema, settings.PortfolioLanguages)...)
+		}
+	}
+
+	metadataIssuesLock.Lock()
+	defer metadataIssuesLock.Unlock()
+	metadataIssues = issues
+	return issues
+}
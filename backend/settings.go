@@ -12,16 +12,45 @@ import (
 )
 
 var ThemeNames = [...]string{"dark", "light"}
+
+// ThemeTextColors maps theme names to the default color of text in that theme.
+var ThemeTextColors = map[string]string{
+	"dark":  "#ffffff",
+	"light": "#000000",
+}
 var TabNames = [...]string{"works", "editor", "tags", "sites", "technologies", "settings"}
 
+var MediaOptimizationFormats = [...]string{"", "png", "jpeg"}
+
 type Settings struct {
-	Theme              string   `json:"theme"`
-	Surname            string   `json:"surname"`
-	ProjectsFolder     string   `json:"projectsfolder"`
-	ShowTips           bool     `json:"showtips"`
-	Language           string   `json:"language"`
-	PortfolioLanguages []string `json:"portfolioLanguages"`
-	PowerUser          bool     `json:"poweruser"`
+	Theme              string                    `json:"theme"`
+	Surname            string                    `json:"surname"`
+	ProjectsFolder     string                    `json:"projectsfolder"`
+	ShowTips           bool                      `json:"showtips"`
+	Language           string                    `json:"language"`
+	PortfolioLanguages []string                  `json:"portfolioLanguages"`
+	PowerUser          bool                      `json:"poweruser"`
+	MediaOptimization  MediaOptimizationSettings `json:"mediaOptimization"`
+	Translation        TranslationSettings       `json:"translation"`
+	// Rebuild works whose description changed outside of ortfo when starting up, instead of only reporting them.
+	RebuildStaleWorksAtStartup bool `json:"rebuildStaleWorksAtStartup"`
+}
+
+// MediaOptimizationSettings configures the web-optimized variants produced when media is added to a work.
+type MediaOptimizationSettings struct {
+	Enabled bool `json:"enabled"`
+	// Format of the variants, one of MediaOptimizationFormats. The empty string keeps the original format.
+	Format  string `json:"format"`
+	Quality int    `json:"quality"` // JPEG quality, from 1 to 100
+	// Maximum dimensions of the variants, in pixels. 0 means no limit.
+	MaxWidth  int `json:"maxWidth"`
+	MaxHeight int `json:"maxHeight"`
+}
+
+// TranslationSettings configures the machine translation server used by the libretranslate provider.
+type TranslationSettings struct {
+	URL    string `json:"url"`
+	APIKey string `json:"apiKey"`
 }
 
 type UIState struct {
@@ -47,12 +76,23 @@ func ConfigurationDirectory(segments ...string) string {
 
 func ValidateSettings(settings Settings) error {
 	// check if theme name is valid
+	validTheme := false
 	for _, themeName := range ThemeNames {
 		if themeName == settings.Theme {
+			validTheme = true
+		}
+	}
+	if !validTheme {
+		return fmt.Errorf("invalid theme name %q, valid theme names are %v", settings.Theme, ThemeNames)
+	}
+
+	// check if media optimization format is valid
+	for _, format := range MediaOptimizationFormats {
+		if format == settings.MediaOptimization.Format {
 			return nil
 		}
 	}
-	return fmt.Errorf("invalid theme name %q, valid theme names are %v", settings.Theme, ThemeNames)
+	return fmt.Errorf("invalid media optimization format %q, valid formats are %q", settings.MediaOptimization.Format, MediaOptimizationFormats)
 }
 
 func SaveSettings(settings Settings) error {
@@ -92,6 +132,14 @@ func DefaultSettings() Settings {
 			return "en"
 		}(),
 		PortfolioLanguages: []string{"en"},
+		MediaOptimization: MediaOptimizationSettings{
+			Quality:   85,
+			MaxWidth:  2560,
+			MaxHeight: 2560,
+		},
+		Translation: TranslationSettings{
+			URL: "http://localhost:5000",
+		},
 	}
 }
 
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	ortfodb "github.com/ortfo/db"
+	"golang.org/x/text/unicode/norm"
+)
+
+// SpreadsheetMapping tells which column of a spreadsheet holds which property of works.
+// Properties mapped to the empty string are not imported.
+// ID defaults to a slug of the first title when the cell is empty.
+// Lists (tags and made with) are split on ListSeparator, which defaults to ",".
+// Without Overwrite, values that would replace a different, non-empty value of an existing work are reported as conflicts and left untouched.
+type SpreadsheetMapping struct {
+	ID             string            `json:"id"`
+	Titles         map[string]string `json:"titles"`
+	Started        string            `json:"started"`
+	Finished       string            `json:"finished"`
+	Tags           string            `json:"tags"`
+	MadeWith       string            `json:"madeWith"`
+	WIP            string            `json:"wip"`
+	Private        string            `json:"private"`
+	PrimaryColor   string            `json:"primaryColor"`
+	SecondaryColor string            `json:"secondaryColor"`
+	TertiaryColor  string            `json:"tertiaryColor"`
+	ListSeparator  string            `json:"listSeparator"`
+	Overwrite      bool              `json:"overwrite"`
+}
+
+// SpreadsheetConflict is a value of a spreadsheet row that differs from the existing work's.
+type SpreadsheetConflict struct {
+	Field    string `json:"field"`
+	Current  string `json:"current"`
+	Incoming string `json:"incoming"`
+}
+
+// Actions taken for spreadsheet rows
+const (
+	SpreadsheetCreate    = "create"
+	SpreadsheetUpdate    = "update"
+	SpreadsheetUnchanged = "unchanged"
+	SpreadsheetSkip      = "skip"
+)
+
+// SpreadsheetRowResult describes what importing a row does, or did.
+// Row is the row's number in the file: for CSV files, the header is row 1.
+// Rows with Errors are skipped.
+type SpreadsheetRowResult struct {
+	Row       int                   `json:"row"`
+	WorkID    string                `json:"workID"`
+	Action    string                `json:"action"`
+	Changes   []string              `json:"changes"`
+	Conflicts []SpreadsheetConflict `json:"conflicts"`
+	Errors    []string              `json:"errors"`
+}
+
+var patternWorkDate = regexp.MustCompile(`^(\d{4}|\?{4})(-(\d{2}|\?{2})(-(\d{2}|\?{2}))?)?$`)
+
+// slugTransliterations are letters that do not decompose into a base letter and accents.
+var slugTransliterations = strings.NewReplacer("ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "đ", "d", "ł", "l", "þ", "th")
+
+// spreadsheetRow maps column names to cell contents.
+type spreadsheetRow struct {
+	number int
+	cells  map[string]string
+}
+
+// readSpreadsheet reads rows from a CSV file with a header row, or from a JSON file containing an array of objects.
+func readSpreadsheet(path string, listSeparator string) ([]spreadsheetRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("while opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	rows := make([]spreadsheetRow, 0)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var objects []map[string]interface{}
+		err = json.NewDecoder(file).Decode(&objects)
+		if err != nil {
+			return nil, fmt.Errorf("while parsing %s: expected an array of objects: %w", path, err)
+		}
+		for i, object := range objects {
+			cells := make(map[string]string, len(object))
+			for column, value := range object {
+				cells[column] = jsonCellString(value, listSeparator)
+			}
+			rows = append(rows, spreadsheetRow{number: i + 1, cells: cells})
+		}
+		return rows, nil
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("while parsing %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return rows, nil
+	}
+	header := records[0]
+	for i, record := range records[1:] {
+		cells := make(map[string]string, len(header))
+		for j, column := range header {
+			if j < len(record) {
+				cells[strings.TrimSpace(column)] = record[j]
+			}
+		}
+		rows = append(rows, spreadsheetRow{number: i + 2, cells: cells})
+	}
+	return rows, nil
+}
+
+func jsonCellString(value interface{}, listSeparator string) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, jsonCellString(item, listSeparator))
+		}
+		return strings.Join(items, listSeparator)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// cell returns the trimmed content of the row's cell in column, or the empty string if column is not mapped.
+func (row spreadsheetRow) cell(column string) string {
+	if column == "" {
+		return ""
+	}
+	return strings.TrimSpace(row.cells[column])
+}
+
+func parseSpreadsheetBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "y", "1", "x", "oui":
+		return true, nil
+	case "false", "no", "n", "0", "non":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not a boolean: use yes or no", value)
+}
+
+func splitSpreadsheetList(value string, separator string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, separator) {
+		if item = strings.TrimSpace(item); item != "" && !stringsLooselyMatch(item, items...) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// slugify turns a title into a work ID. Accents are removed, and letters of other scripts are kept,
+// so that titles that only differ by their non-ASCII letters get different IDs.
+func slugify(title string) string {
+	decomposed := norm.NFD.String(slugTransliterations.Replace(strings.ToLower(title)))
+	var slug strings.Builder
+	separate := false
+	for _, char := range decomposed {
+		switch {
+		case unicode.Is(unicode.Mn, char):
+			// Accents
+			continue
+		case unicode.IsLetter(char) || unicode.IsDigit(char):
+			if separate && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			separate = false
+			slug.WriteRune(char)
+		default:
+			separate = true
+		}
+	}
+	return norm.NFC.String(slug.String())
+}
+
+// spreadsheetRowApplier applies the values of a row to a work, recording changes and conflicts.
+type spreadsheetRowApplier struct {
+	result    *SpreadsheetRowResult
+	existing  bool
+	overwrite bool
+}
+
+// set changes *current to incoming, unless that would be a conflict.
+func (a spreadsheetRowApplier) set(field string, current *string, incoming string) {
+	if incoming == "" || *current == incoming {
+		return
+	}
+	if a.existing && *current != "" && !a.overwrite {
+		a.result.Conflicts = append(a.result.Conflicts, SpreadsheetConflict{Field: field, Current: *current, Incoming: incoming})
+		return
+	}
+	*current = incoming
+	a.result.Changes = append(a.result.Changes, field)
+}
+
+// setList adds the incoming items to *current. With overwrite, *current is replaced.
+func (a spreadsheetRowApplier) setList(field string, current *[]string, incoming []string) {
+	if len(incoming) == 0 {
+		return
+	}
+	updated := incoming
+	if !a.overwrite {
+		updated = append([]string{}, *current...)
+		for _, item := range incoming {
+			if !stringsLooselyMatch(item, updated...) {
+				updated = append(updated, item)
+			}
+		}
+	}
+	if strings.Join(updated, "\x00") != strings.Join(*current, "\x00") {
+		*current = updated
+		a.result.Changes = append(a.result.Changes, field)
+	}
+}
+
+// setBool changes *current to incoming. Like empty strings for set, false is treated as unset:
+// it only replaces true with Overwrite, and setting a false value of an existing work is not a conflict.
+func (a spreadsheetRowApplier) setBool(field string, current *bool, incoming bool) {
+	if *current == incoming || (!incoming && !a.overwrite) {
+		return
+	}
+	if a.existing && *current && !a.overwrite {
+		a.result.Conflicts = append(a.result.Conflicts, SpreadsheetConflict{Field: field, Current: strconv.FormatBool(*current), Incoming: strconv.FormatBool(incoming)})
+		return
+	}
+	*current = incoming
+	a.result.Changes = append(a.result.Changes, field)
+}
+
+// ImportSpreadsheet creates or updates works from the rows of a CSV or JSON file, using mapping to find properties in columns.
+// Created works are only written to their description.md, and are added to the database by the next rebuild.
+// With dryRun, nothing is written and the results describe what would be done.
+func (settings *Settings) ImportSpreadsheet(path string, mapping SpreadsheetMapping, dryRun bool) ([]SpreadsheetRowResult, error) {
+	if mapping.ListSeparator == "" {
+		mapping.ListSeparator = ","
+	}
+	rows, err := readSpreadsheet(path, mapping.ListSeparator)
+	if err != nil {
+		return nil, err
+	}
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("while loading database: %w", err)
+	}
+
+	// Titles are taken in the order of the portfolio's languages, so that IDs are made from the title in the first of them.
+	languages := make([]string, 0, len(mapping.Titles))
+	for lang := range mapping.Titles {
+		languages = append(languages, lang)
+	}
+	rank := func(lang string) int {
+		for i, portfolioLanguage := range settings.PortfolioLanguages {
+			if portfolioLanguage == lang {
+				return i
+			}
+		}
+		return len(settings.PortfolioLanguages)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if rank(languages[i]) != rank(languages[j]) {
+			return rank(languages[i]) < rank(languages[j])
+		}
+		return languages[i] < languages[j]
+	})
+
+	results := make([]SpreadsheetRowResult, 0, len(rows))
+	rowOfWork := make(map[string]int)
+	anyUpdated := false
+	for _, row := range rows {
+		result := SpreadsheetRowResult{Row: row.number, Changes: make([]string, 0), Conflicts: make([]SpreadsheetConflict, 0), Errors: make([]string, 0)}
+		fail := func(message string, a ...interface{}) {
+			result.Errors = append(result.Errors, fmt.Sprintf(message, a...))
+		}
+
+		result.WorkID = row.cell(mapping.ID)
+		for _, lang := range languages {
+			if result.WorkID != "" {
+				break
+			}
+			result.WorkID = slugify(row.cell(mapping.Titles[lang]))
+		}
+		if result.WorkID == "" {
+			fail("no work ID, and no title to make one from")
+		} else if strings.ContainsAny(result.WorkID, `/\`) || strings.HasPrefix(result.WorkID, ".") {
+			fail("invalid work ID %q", result.WorkID)
+		} else if previous, ok := rowOfWork[result.WorkID]; ok {
+			fail("work %s is already described by row %d", result.WorkID, previous)
+		}
+
+		for _, field := range []struct{ name, value string }{{"started", row.cell(mapping.Started)}, {"finished", row.cell(mapping.Finished)}} {
+			if field.value != "" && !patternWorkDate.MatchString(field.value) {
+				fail("%s: %q is not a date of the form YYYY-MM-DD", field.name, field.value)
+			}
+		}
+		flags := make(map[string]bool)
+		for _, field := range []struct{ name, value string }{{"wip", row.cell(mapping.WIP)}, {"private", row.cell(mapping.Private)}} {
+			if field.value == "" {
+				continue
+			}
+			value, err := parseSpreadsheetBool(field.value)
+			if err != nil {
+				fail("%s: %s", field.name, err)
+			}
+			flags[field.name] = value
+		}
+		colors := make(map[string]string)
+		for _, field := range []struct{ name, value string }{{"primary color", row.cell(mapping.PrimaryColor)}, {"secondary color", row.cell(mapping.SecondaryColor)}, {"tertiary color", row.cell(mapping.TertiaryColor)}} {
+			if field.value == "" {
+				continue
+			}
+			if _, _, _, err := parseHexColor(field.value); err != nil {
+				fail("%s: %s", field.name, err)
+			}
+			colors[field.name] = "#" + strings.TrimPrefix(field.value, "#")
+		}
+
+		if len(result.Errors) > 0 {
+			result.Action = SpreadsheetSkip
+			results = append(results, result)
+			continue
+		}
+		rowOfWork[result.WorkID] = row.number
+
+		work, exists := db[result.WorkID]
+		if !exists {
+			work = ortfodb.Work{
+				ID:       result.WorkID,
+				Metadata: ortfodb.WorkMetadata{MadeWith: make([]string, 0), Tags: make([]string, 0)},
+			}
+		}
+		if work.Content == nil {
+			work.Content = make(ortfodb.LocalizableContent)
+		}
+		descriptionChanged, err := settings.descriptionChanged(work, result.WorkID)
+		if err == nil && descriptionChanged {
+			err = DescriptionsChangedError{WorkIDs: []string{result.WorkID}}
+		}
+		if err != nil {
+			fail("%s", err)
+			result.Action = SpreadsheetSkip
+			results = append(results, result)
+			continue
+		}
+		apply := spreadsheetRowApplier{result: &result, existing: exists, overwrite: mapping.Overwrite}
+
+		for _, lang := range languages {
+			title := row.cell(mapping.Titles[lang])
+			if title == "" {
+				continue
+			}
+			content := work.Content[lang]
+			current := html.UnescapeString(string(content.Title))
+			apply.set("title ("+lang+")", &current, title)
+			if current != html.UnescapeString(string(content.Title)) {
+				content.Title = ortfodb.HTMLString(html.EscapeString(current))
+				work.Content[lang] = content
+			}
+		}
+		apply.set("started", &work.Metadata.Started, row.cell(mapping.Started))
+		apply.set("finished", &work.Metadata.Finished, row.cell(mapping.Finished))
+		apply.setList("tags", &work.Metadata.Tags, splitSpreadsheetList(row.cell(mapping.Tags), mapping.ListSeparator))
+		apply.setList("made with", &work.Metadata.MadeWith, splitSpreadsheetList(row.cell(mapping.MadeWith), mapping.ListSeparator))
+		if value, ok := flags["wip"]; ok {
+			apply.setBool("wip", &work.Metadata.WIP, value)
+		}
+		if value, ok := flags["private"]; ok {
+			apply.setBool("private", &work.Metadata.Private, value)
+		}
+		apply.set("primary color", &work.Metadata.Colors.Primary, colors["primary color"])
+		apply.set("secondary color", &work.Metadata.Colors.Secondary, colors["secondary color"])
+		apply.set("tertiary color", &work.Metadata.Colors.Tertiary, colors["tertiary color"])
+
+		switch {
+		case !exists:
+			result.Action = SpreadsheetCreate
+		case len(result.Changes) > 0:
+			result.Action = SpreadsheetUpdate
+		default:
+			result.Action = SpreadsheetUnchanged
+		}
+
+		// Created works are only written to their description.md: they are not built yet,
+		// so they are left out of the database for the next rebuild to build their content and media.
+		if !dryRun {
+			switch result.Action {
+			case SpreadsheetCreate:
+				err = Writeback(*settings, work, result.WorkID)
+				if err != nil {
+					result.Action = SpreadsheetSkip
+					fail("while writing description: %s", err)
+				}
+			case SpreadsheetUpdate:
+				err = settings.writebackWork(&work, result.WorkID)
+				if err != nil {
+					result.Action = SpreadsheetSkip
+					fail("while writing back description: %s", err)
+				} else {
+					db[result.WorkID] = work
+					anyUpdated = true
+				}
+			}
+		}
+		results = append(results, result)
+	}
+
+	if anyUpdated {
+		ctx.WriteDatabase(db, ctx.Flags, ctx.OutputDatabaseFile, db.Partial())
+	}
+	return results, nil
+}
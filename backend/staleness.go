@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+// DatabaseStaleness lists the works for which the database does not reflect the descriptions on disk.
+// Outdated works have a description.md that changed since they were built,
+// Unbuilt works have a description.md but are not in the database,
+// and Removed works are in the database but have no description.md anymore.
+type DatabaseStaleness struct {
+	Outdated []string `json:"outdated"`
+	Unbuilt  []string `json:"unbuilt"`
+	Removed  []string `json:"removed"`
+}
+
+// Stale returns true if some works need to be rebuilt or removed from the database.
+func (s DatabaseStaleness) Stale() bool {
+	return len(s.Outdated)+len(s.Unbuilt)+len(s.Removed) > 0
+}
+
+// describedWorks returns the IDs of the works of the projects folder that have a description.md.
+func (settings *Settings) describedWorks() ([]string, error) {
+	entries, err := os.ReadDir(JoinPaths(settings.ProjectsFolder))
+	if err != nil {
+		return nil, fmt.Errorf("while listing projects folder: %w", err)
+	}
+	workIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(JoinPaths(settings.ProjectsFolder, entry.Name(), ".ortfo", "description.md")); err == nil {
+			workIDs = append(workIDs, entry.Name())
+		}
+	}
+	return workIDs, nil
+}
+
+// DatabaseStaleness compares the descriptions on disk with the database, using the hashes of description.md files, as ortfodb does.
+func (settings *Settings) DatabaseStaleness(db ortfodb.Database) (DatabaseStaleness, error) {
+	staleness := DatabaseStaleness{Outdated: make([]string, 0), Unbuilt: make([]string, 0), Removed: make([]string, 0)}
+	onDisk, err := settings.describedWorks()
+	if err != nil {
+		return staleness, err
+	}
+
+	for _, workID := range onDisk {
+		work, ok := db[workID]
+		if !ok {
+			staleness.Unbuilt = append(staleness.Unbuilt, workID)
+			continue
+		}
+		hash, err := hashFile(JoinPaths(settings.ProjectsFolder, workID, ".ortfo", "description.md"))
+		if err != nil {
+			return staleness, fmt.Errorf("while hashing description of %s: %w", workID, err)
+		}
+		if hash != work.DescriptionHash {
+			staleness.Outdated = append(staleness.Outdated, workID)
+		}
+	}
+	for workID := range db {
+		if !containsString(onDisk, workID) {
+			staleness.Removed = append(staleness.Removed, workID)
+		}
+	}
+
+	sort.Strings(staleness.Outdated)
+	sort.Strings(staleness.Unbuilt)
+	sort.Strings(staleness.Removed)
+	return staleness, nil
+}
+
+// CheckDatabaseStaleness loads the database and compares it with the descriptions on disk, see DatabaseStaleness.
+func (settings *Settings) CheckDatabaseStaleness() (DatabaseStaleness, error) {
+	db, err := ortfodb.LoadDatabase(ConfigurationDirectory("portfolio-database", "database.json"), true)
+	if err != nil {
+		return DatabaseStaleness{}, fmt.Errorf("while loading database: %w", err)
+	}
+	return settings.DatabaseStaleness(db)
+}
+
+// RebuildStaleWorks rebuilds outdated and unbuilt works, and removes works that have no description anymore from the database.
+// Other works are left untouched. It returns what was stale.
+func (settings *Settings) RebuildStaleWorks() (DatabaseStaleness, error) {
+	databaseFile := ConfigurationDirectory("portfolio-database", "database.json")
+	db, err := ortfodb.LoadDatabase(databaseFile, true)
+	if err != nil {
+		return DatabaseStaleness{}, fmt.Errorf("while loading database: %w", err)
+	}
+	staleness, err := settings.DatabaseStaleness(db)
+	if err != nil || !staleness.Stale() {
+		return staleness, err
+	}
+
+	// Build on top of the current database, not of the one loaded when the context was prepared.
+	// Works whose description hash did not change are reused by ortfodb, so building every work only rebuilds the stale ones,
+	// in a single pass over the projects folder.
+	for _, workID := range staleness.Removed {
+		delete(db, workID)
+	}
+	ctx.PreviousBuiltDatabase = db
+	flags := ctx.Flags
+	flags.NoCache = false
+	built, err := ctx.BuildSome("*", projectsFolder(), databaseFile, flags, *ctx.Config)
+	if err != nil {
+		return staleness, fmt.Errorf("while rebuilding stale works: %w", err)
+	}
+	ctx.PreviousBuiltDatabase = built
+	ctx.WriteDatabase(built, flags, databaseFile, false)
+	settings.validateMetadataAfterRebuild(built)
+	return staleness, nil
+}
+
+// StartupStalenessCheck is the state of the check of the database against the descriptions on disk done at startup.
+// Rebuilt is true if stale works were rebuilt because of the RebuildStaleWorksAtStartup setting.
+type StartupStalenessCheck struct {
+	Running   bool              `json:"running"`
+	Rebuilt   bool              `json:"rebuilt"`
+	Staleness DatabaseStaleness `json:"staleness"`
+	Error     string            `json:"error"`
+}
+
+var startupStalenessCheck StartupStalenessCheck
+var startupStalenessCheckLock sync.Mutex
+
+func setStartupStalenessCheck(update func(check *StartupStalenessCheck)) {
+	startupStalenessCheckLock.Lock()
+	defer startupStalenessCheckLock.Unlock()
+	update(&startupStalenessCheck)
+}
+
+// StartupStalenessCheckState returns a snapshot of the startup staleness check, so that the frontend can offer to rebuild stale works.
+func StartupStalenessCheckState() StartupStalenessCheck {
+	startupStalenessCheckLock.Lock()
+	defer startupStalenessCheckLock.Unlock()
+	return startupStalenessCheck
+}
+
+// checkStalenessAtStartup finds works for which the database is out of date, and rebuilds them if the settings say so.
+// It is meant to run in the background, its outcome is available through StartupStalenessCheckState.
+func (settings *Settings) checkStalenessAtStartup() {
+	if _, err := os.Stat(ConfigurationDirectory("portfolio-database", "database.json")); os.IsNotExist(err) {
+		return
+	}
+	setStartupStalenessCheck(func(check *StartupStalenessCheck) {
+		*check = StartupStalenessCheck{Running: true}
+	})
+
+	var staleness DatabaseStaleness
+	var err error
+	if settings.RebuildStaleWorksAtStartup {
+		staleness, err = settings.RebuildStaleWorks()
+	} else {
+		staleness, err = settings.CheckDatabaseStaleness()
+	}
+
+	setStartupStalenessCheck(func(check *StartupStalenessCheck) {
+		check.Running = false
+		check.Staleness = staleness
+		if err != nil {
+			check.Error = err.Error()
+		} else {
+			check.Rebuilt = settings.RebuildStaleWorksAtStartup && staleness.Stale()
+		}
+	})
+}
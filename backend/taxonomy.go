@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TaxonomyProblem is a problem found while reading tags.yaml, technologies.yaml or sites.yaml.
+// Index is the position of the offending entry in the file, or -1 if the problem concerns the whole file.
+type TaxonomyProblem struct {
+	File    string `json:"file"`
+	Index   int    `json:"index"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// TagsReadResult holds the tags read from tags.yaml, along with the problems found in them.
+type TagsReadResult struct {
+	Tags     []Tag             `json:"tags"`
+	Problems []TaxonomyProblem `json:"problems"`
+}
+
+// TechnologiesReadResult holds the technologies read from technologies.yaml, along with the problems found in them.
+type TechnologiesReadResult struct {
+	Technologies []Technology      `json:"technologies"`
+	Problems     []TaxonomyProblem `json:"problems"`
+}
+
+// ExternalSitesReadResult holds the sites read from sites.yaml, along with the problems found in them.
+type ExternalSitesReadResult struct {
+	Sites    []ExternalSite    `json:"sites"`
+	Problems []TaxonomyProblem `json:"problems"`
+}
+
+// LoadTags reads tags.yaml.
+func LoadTags() ([]Tag, error) {
+	tags := make([]Tag, 0)
+	raw, err := os.ReadFile(ConfigurationDirectory("portfolio-database", "tags.yaml"))
+	if err != nil {
+		return tags, fmt.Errorf("while reading tags: %w", err)
+	}
+	err = yaml.Unmarshal(raw, &tags)
+	if err != nil {
+		return tags, fmt.Errorf("while parsing tags: %w", err)
+	}
+	return tags, nil
+}
+
+// LoadTechnologies reads technologies.yaml.
+func LoadTechnologies() ([]Technology, error) {
+	technologies := make([]Technology, 0)
+	raw, err := os.ReadFile(ConfigurationDirectory("portfolio-database", "technologies.yaml"))
+	if err != nil {
+		return technologies, fmt.Errorf("while reading technologies: %w", err)
+	}
+	err = yaml.Unmarshal(raw, &technologies)
+	if err != nil {
+		return technologies, fmt.Errorf("while parsing technologies: %w", err)
+	}
+	return technologies, nil
+}
+
+// SaveTags writes tags to tags.yaml.
+func SaveTags(tags []Tag) error {
+	tagsBytes, err := yaml.Marshal(tags)
+	if err != nil {
+		return fmt.Errorf("while converting to YAML: %w", err)
+	}
+
+	return os.WriteFile(ConfigurationDirectory("portfolio-database", "tags.yaml"), tagsBytes, 0644)
+}
+
+// SaveTechnologies writes technologies to technologies.yaml.
+func SaveTechnologies(technologies []Technology) error {
+	technologiesBytes, err := yaml.Marshal(technologies)
+	if err != nil {
+		return fmt.Errorf("while converting to YAML: %w", err)
+	}
+
+	return os.WriteFile(ConfigurationDirectory("portfolio-database", "technologies.yaml"), technologiesBytes, 0644)
+}
+
+// readTaxonomyFile parses the given file of portfolio-database into entries.
+// Syntax and type errors are reported as problems: entries that could be decoded are kept.
+// Only I/O errors are returned as errors.
+func readTaxonomyFile(filename string, entries interface{}) ([]TaxonomyProblem, error) {
+	problems := make([]TaxonomyProblem, 0)
+	raw, err := os.ReadFile(ConfigurationDirectory("portfolio-database", filename))
+	if err != nil {
+		return problems, fmt.Errorf("while reading %s: %w", filename, err)
+	}
+
+	err = yaml.Unmarshal(raw, entries)
+	if typeError, ok := err.(*yaml.TypeError); ok {
+		for _, message := range typeError.Errors {
+			problems = append(problems, TaxonomyProblem{File: filename, Index: -1, Message: message})
+		}
+	} else if err != nil {
+		problems = append(problems, TaxonomyProblem{File: filename, Index: -1, Message: strings.TrimPrefix(err.Error(), "yaml: ")})
+	}
+	return problems, nil
+}
+
+// validateURL returns a description of what is wrong with rawURL, or the empty string if it is a valid HTTP(S) URL.
+func validateURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Sprintf("malformed URL %q: %s", rawURL, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Sprintf("URL %q should start with http:// or https://", rawURL)
+	}
+	if parsed.Host == "" {
+		return fmt.Sprintf("URL %q has no domain name", rawURL)
+	}
+	return ""
+}
+
+// names returns every name the tag can be referred to by.
+func (t Tag) names() []string {
+	names := make([]string, 0)
+	for _, name := range append(append(t.Singular.Values(), t.Plural.Values()...), t.Aliases...) {
+		if strings.TrimSpace(name) != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// names returns every name the technology can be referred to by.
+func (t Technology) names() []string {
+	names := make([]string, 0)
+	for _, name := range append([]string{t.Slug, t.Name}, t.Aliases...) {
+		if strings.TrimSpace(name) != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ValidateTags checks that every tag has names, that no two tags can be referred to by the same name and that links are valid.
+func ValidateTags(tags []Tag) []TaxonomyProblem {
+	problems := make([]TaxonomyProblem, 0)
+	problem := func(index int, field string, message string, a ...interface{}) {
+		problems = append(problems, TaxonomyProblem{File: "tags.yaml", Index: index, Field: field, Message: fmt.Sprintf(message, a...)})
+	}
+
+	for i, tag := range tags {
+		if tag.Singular.Empty() {
+			problem(i, "singular", "missing singular")
+		}
+		if tag.Plural.Empty() {
+			problem(i, "plural", "missing plural for tag %q", strings.Join(tag.Singular.Values(), ", "))
+		} else if tag.Singular.IsLocalized() {
+			for lang := range tag.Singular.Localized {
+				if tag.Plural.IsLocalized() && strings.TrimSpace(tag.Plural.In(lang)) == "" {
+					problem(i, "plural", "missing plural in %s for tag %q", lang, tag.Singular.In(lang))
+				}
+			}
+		}
+		if tag.LearnMoreAt != "" {
+			if message := validateURL(tag.LearnMoreAt); message != "" {
+				problem(i, "learnMoreAt", message)
+			}
+		}
+		for j := 0; j < i; j++ {
+			for _, name := range tag.names() {
+				if tags[j].ReferredToBy(name) {
+					problem(i, "aliases", "%q already refers to tag #%d (%s)", name, j+1, strings.Join(tags[j].Singular.Values(), ", "))
+					break
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// ValidateTechnologies checks that every technology has a unique slug and a name, that no two technologies can be referred to by the same name and that links are valid.
+func ValidateTechnologies(technologies []Technology) []TaxonomyProblem {
+	problems := make([]TaxonomyProblem, 0)
+	problem := func(index int, field string, message string, a ...interface{}) {
+		problems = append(problems, TaxonomyProblem{File: "technologies.yaml", Index: index, Field: field, Message: fmt.Sprintf(message, a...)})
+	}
+
+	for i, technology := range technologies {
+		if strings.TrimSpace(technology.Slug) == "" {
+			problem(i, "slug", "missing slug")
+		}
+		if strings.TrimSpace(technology.Name) == "" {
+			problem(i, "name", "missing name for technology %q", technology.Slug)
+		}
+		if technology.LearnMoreAt != "" {
+			if message := validateURL(technology.LearnMoreAt); message != "" {
+				problem(i, "learnMoreAt", message)
+			}
+		}
+		for j := 0; j < i; j++ {
+			if technology.Slug != "" && strings.EqualFold(technology.Slug, technologies[j].Slug) {
+				problem(i, "slug", "duplicate slug %q, already used by technology #%d", technology.Slug, j+1)
+				continue
+			}
+			for _, name := range technology.names() {
+				if technologies[j].ReferredToBy(name) {
+					problem(i, "aliases", "%q already refers to technology %q", name, technologies[j].Slug)
+					break
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// ValidateExternalSites checks that every site has a unique name and a valid URL.
+func ValidateExternalSites(sites []ExternalSite) []TaxonomyProblem {
+	problems := make([]TaxonomyProblem, 0)
+	problem := func(index int, field string, message string, a ...interface{}) {
+		problems = append(problems, TaxonomyProblem{File: "sites.yaml", Index: index, Field: field, Message: fmt.Sprintf(message, a...)})
+	}
+
+	for i, site := range sites {
+		if strings.TrimSpace(site.Name) == "" {
+			problem(i, "name", "missing name")
+		}
+		if strings.TrimSpace(site.URL) == "" {
+			problem(i, "url", "missing URL for site %q", site.Name)
+		} else if message := validateURL(site.URL); message != "" {
+			problem(i, "url", message)
+		}
+		for j := 0; j < i; j++ {
+			if site.Name != "" && strings.EqualFold(site.Name, sites[j].Name) {
+				problem(i, "name", "duplicate site %q, already declared as site #%d", site.Name, j+1)
+				break
+			}
+		}
+	}
+	return problems
+}
+
+// ReadTags reads and validates tags.yaml.
+// Tags are returned even if they have problems, so that they can be fixed from the UI.
+func ReadTags() (TagsReadResult, error) {
+	tags := make([]Tag, 0)
+	problems, err := readTaxonomyFile("tags.yaml", &tags)
+	if err != nil {
+		return TagsReadResult{}, err
+	}
+	return TagsReadResult{Tags: tags, Problems: append(problems, ValidateTags(tags)...)}, nil
+}
+
+// ReadTechnologies reads and validates technologies.yaml.
+// Technologies are returned even if they have problems, so that they can be fixed from the UI.
+func ReadTechnologies() (TechnologiesReadResult, error) {
+	technologies := make([]Technology, 0)
+	problems, err := readTaxonomyFile("technologies.yaml", &technologies)
+	if err != nil {
+		return TechnologiesReadResult{}, err
+	}
+	return TechnologiesReadResult{Technologies: technologies, Problems: append(problems, ValidateTechnologies(technologies)...)}, nil
+}
+
+// ReadExternalSites reads and validates sites.yaml.
+// Sites are returned even if they have problems, so that they can be fixed from the UI.
+func ReadExternalSites() (ExternalSitesReadResult, error) {
+	sites := make([]ExternalSite, 0)
+	problems, err := readTaxonomyFile("sites.yaml", &sites)
+	if err != nil {
+		return ExternalSitesReadResult{}, err
+	}
+	return ExternalSitesReadResult{Sites: sites, Problems: append(problems, ValidateExternalSites(sites)...)}, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+// Translator translates text from a language to another.
+// format is either "text" or "html". HTML translators must keep tags and attributes intact.
+type Translator interface {
+	Translate(text string, from string, to string, format string) (string, error)
+}
+
+// Translators maps provider names to constructors of their Translator.
+var Translators = map[string]func(settings Settings) Translator{
+	"libretranslate": func(settings Settings) Translator {
+		return LibreTranslate{
+			URL:    settings.Translation.URL,
+			APIKey: settings.Translation.APIKey,
+			Client: &http.Client{Timeout: 30 * time.Second},
+		}
+	},
+	"noop": func(settings Settings) Translator {
+		return NoopTranslator{}
+	},
+}
+
+// NoopTranslator returns text untranslated.
+type NoopTranslator struct{}
+
+func (NoopTranslator) Translate(text string, from string, to string, format string) (string, error) {
+	return text, nil
+}
+
+// LibreTranslate translates text with a LibreTranslate server (see https://github.com/LibreTranslate/LibreTranslate), that can be run locally.
+type LibreTranslate struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func (t LibreTranslate) Translate(text string, from string, to string, format string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return text, nil
+	}
+	if t.URL == "" {
+		return "", fmt.Errorf("no LibreTranslate server URL configured")
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"q":       text,
+		"source":  from,
+		"target":  to,
+		"format":  format,
+		"api_key": t.APIKey,
+	})
+	if err != nil {
+		return "", fmt.Errorf("while encoding request: %w", err)
+	}
+
+	response, err := t.Client.Post(strings.TrimSuffix(t.URL, "/")+"/translate", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("while contacting %s: %w", t.URL, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		// Errors are JSON objects with an error message, but proxies in front of the server may respond with anything.
+		raw, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		var failure struct {
+			Error string `json:"error"`
+		}
+		message := strings.TrimSpace(string(raw))
+		if json.Unmarshal(raw, &failure) == nil && failure.Error != "" {
+			message = failure.Error
+		}
+		return "", fmt.Errorf("%s responded with %s: %s", t.URL, response.Status, message)
+	}
+
+	var result struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return "", fmt.Errorf("while decoding response from %s: %w", t.URL, err)
+	}
+	return result.TranslatedText, nil
+}
+
+// Footnote references are replaced with placeholders during translation so that translators can't mangle them.
+var patternFootnoteReferenceElement = regexp.MustCompile(`<sup class="footnote-ref"[^>]*>.*?</sup>|\[\^[^\s\]]+\]`)
+
+func translateHTML(translator Translator, html string, from string, to string) (string, error) {
+	protected := make([]string, 0)
+	html = patternFootnoteReferenceElement.ReplaceAllStringFunc(html, func(reference string) string {
+		protected = append(protected, reference)
+		return fmt.Sprintf(`<span translate="no">ortfo-footnote-%d</span>`, len(protected)-1)
+	})
+
+	translated, err := translator.Translate(html, from, to, "html")
+	if err != nil {
+		return "", err
+	}
+
+	for i, reference := range protected {
+		placeholder := fmt.Sprintf(`<span translate="no">ortfo-footnote-%d</span>`, i)
+		if count := strings.Count(translated, placeholder); count != 1 {
+			return "", fmt.Errorf("the translation has %d instead of 1 copy of footnote reference #%d, it was probably mangled by the translator", count, i+1)
+		}
+		translated = strings.Replace(translated, placeholder, reference, 1)
+	}
+	return translated, nil
+}
+
+// TranslateWork creates the toLang content of the work with ID workID, by translating its fromLang content with the given provider
+// (see Translators), and writes the description back.
+// Paragraphs, titles, footnotes, captions, alt texts and link texts are translated.
+func (settings *Settings) TranslateWork(workID string, fromLang string, toLang string, provider string) error {
+	makeTranslator, ok := Translators[provider]
+	if !ok {
+		return fmt.Errorf("unknown translation provider %q", provider)
+	}
+	translator := makeTranslator(*settings)
+
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return fmt.Errorf("while loading database: %w", err)
+	}
+	work, ok := db[workID]
+	if !ok {
+		return fmt.Errorf("no work with ID %q in the database", workID)
+	}
+	source, ok := work.Content[fromLang]
+	if !ok {
+		return fmt.Errorf("work %s has no content in %s", workID, fromLang)
+	}
+	if _, exists := work.Content[toLang]; exists {
+		return fmt.Errorf("work %s already has content in %s", workID, toLang)
+	}
+
+	translated := ortfodb.LocalizedContent{
+		Layout:    source.Layout,
+		Blocks:    make([]ortfodb.ContentBlock, len(source.Blocks)),
+		Footnotes: make(ortfodb.Footnotes),
+	}
+
+	title, err := translateHTML(translator, string(source.Title), fromLang, toLang)
+	if err != nil {
+		return fmt.Errorf("while translating title: %w", err)
+	}
+	translated.Title = ortfodb.HTMLString(title)
+
+	for name, footnote := range source.Footnotes {
+		content, err := translateHTML(translator, string(footnote), fromLang, toLang)
+		if err != nil {
+			return fmt.Errorf("while translating footnote %s: %w", name, err)
+		}
+		translated.Footnotes[name] = ortfodb.HTMLString(content)
+	}
+
+	for i, block := range source.Blocks {
+		switch {
+		case block.Type.IsParagraph():
+			content, err := translateHTML(translator, string(block.Content), fromLang, toLang)
+			if err != nil {
+				return fmt.Errorf("while translating paragraph %s: %w", block.ID, err)
+			}
+			block.Content = ortfodb.HTMLString(content)
+		case block.Type.IsMedia():
+			block.Alt, err = translator.Translate(block.Alt, fromLang, toLang, "text")
+			if err != nil {
+				return fmt.Errorf("while translating alt text of %s: %w", block.ID, err)
+			}
+			block.Caption, err = translator.Translate(block.Caption, fromLang, toLang, "text")
+			if err != nil {
+				return fmt.Errorf("while translating caption of %s: %w", block.ID, err)
+			}
+		case block.Type.IsLink():
+			text, err := translateHTML(translator, string(block.Text), fromLang, toLang)
+			if err != nil {
+				return fmt.Errorf("while translating link %s: %w", block.ID, err)
+			}
+			block.Text = ortfodb.HTMLString(text)
+		}
+		translated.Blocks[i] = block
+	}
+
+	previous := work
+	previous.Content = make(ortfodb.LocalizableContent, len(work.Content))
+	for lang, content := range work.Content {
+		previous.Content[lang] = content
+	}
+	work.Content[toLang] = translated
+	err = Writeback(*settings, work, workID)
+	if err != nil {
+		return err
+	}
+	return settings.recordTranslations(workID, previous, work)
+}
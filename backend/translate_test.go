@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Tags and elements marked with translate="no" are left as is by translators.
+var patternUntranslated = regexp.MustCompile(`<span translate="no">.*?</span>|<[^>]*>`)
+
+// newLibreTranslateTestServer stands in for a LibreTranslate server. It "translates" by upper-casing text (see patternUntranslated),
+// and responds with errors for texts containing "fail" or "proxy".
+func newLibreTranslateTestServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "malformed request"})
+			return
+		}
+		if strings.Contains(request["q"], "fail") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": request["target"] + " is not supported"})
+			return
+		}
+		if strings.Contains(request["q"], "proxy") {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>Bad gateway</html>"))
+			return
+		}
+		var translated strings.Builder
+		text := request["q"]
+		end := 0
+		for _, kept := range patternUntranslated.FindAllStringIndex(text, -1) {
+			translated.WriteString(strings.ToUpper(text[end:kept[0]]))
+			translated.WriteString(text[kept[0]:kept[1]])
+			end = kept[1]
+		}
+		translated.WriteString(strings.ToUpper(text[end:]))
+		json.NewEncoder(w).Encode(map[string]string{"translatedText": translated.String()})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTranslateHTMLKeepsFootnoteReferences(t *testing.T) {
+	server := newLibreTranslateTestServer(t)
+	translator := LibreTranslate{URL: server.URL, Client: server.Client()}
+
+	source := `see this<sup class="footnote-ref"><a href="#fn1" id="fnref1">1</a></sup> and that[^note]`
+	translated, err := translateHTML(translator, source, "en", "fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `SEE THIS<sup class="footnote-ref"><a href="#fn1" id="fnref1">1</a></sup> AND THAT[^note]`
+	if translated != expected {
+		t.Errorf("got %q, expected %q", translated, expected)
+	}
+
+	translated, err = translateHTML(NoopTranslator{}, source, "en", "fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if translated != source {
+		t.Errorf("got %q, expected %q", translated, source)
+	}
+}
+
+// manglingTranslator drops the placeholders of footnote references, like translators that do not know about translate="no".
+type manglingTranslator struct{}
+
+func (manglingTranslator) Translate(text string, from string, to string, format string) (string, error) {
+	return regexp.MustCompile(`<span translate="no">ortfo-footnote-\d+</span>`).ReplaceAllString(text, ""), nil
+}
+
+func TestTranslateHTMLRefusesMangledFootnoteReferences(t *testing.T) {
+	_, err := translateHTML(manglingTranslator{}, `see this[^note]`, "en", "fr")
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestLibreTranslateReportsServerErrors(t *testing.T) {
+	server := newLibreTranslateTestServer(t)
+	translator := LibreTranslate{URL: server.URL, Client: server.Client()}
+
+	_, err := translator.Translate("this will fail", "en", "xx", "text")
+	if err == nil || !strings.Contains(err.Error(), "xx is not supported") {
+		t.Errorf("expected the server's error message, got %v", err)
+	}
+
+	_, err = translator.Translate("behind a proxy", "en", "fr", "text")
+	if err == nil || !strings.Contains(err.Error(), "502") || !strings.Contains(err.Error(), "Bad gateway") {
+		t.Errorf("expected the status and body of the response, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	ortfodb "github.com/ortfo/db"
+)
+
+// TranslationStatus describes how up to date a work's content in some language is, compared to the reference language
+// (the first of the portfolio's languages).
+type TranslationStatus struct {
+	Exists          bool `json:"exists"`
+	Blocks          int  `json:"blocks"`
+	ReferenceBlocks int  `json:"referenceBlocks"`
+	// IDs of the reference language's paragraphs that were added or modified since this translation was last saved.
+	Outdated []string `json:"outdated"`
+}
+
+// translationSnapshot records the IDs of the reference language's paragraphs as they were when a translation was last saved.
+// Paragraph IDs are derived from their content, so a paragraph that was modified since then has an ID that is not in the snapshot.
+type translationSnapshot struct {
+	ReferenceParagraphs []string `json:"referenceParagraphs"`
+}
+
+// translationSnapshots maps work IDs to languages to snapshots.
+type translationSnapshots map[string]map[string]translationSnapshot
+
+func loadTranslationSnapshots() translationSnapshots {
+	snapshots := make(translationSnapshots)
+	raw, err := os.ReadFile(ConfigurationDirectory("portfolio-database", "translations.json"))
+	if err != nil {
+		return snapshots
+	}
+	err = json.Unmarshal(raw, &snapshots)
+	if err != nil {
+		return make(translationSnapshots)
+	}
+	return snapshots
+}
+
+func (snapshots translationSnapshots) save() error {
+	raw, err := json.Marshal(snapshots)
+	if err != nil {
+		return fmt.Errorf("while converting translation snapshots to JSON: %w", err)
+	}
+	return os.WriteFile(ConfigurationDirectory("portfolio-database", "translations.json"), raw, 0644)
+}
+
+// renameTranslationSnapshots changes the languages of the translation snapshots of every work, see changeKeys.
+// Languages renamed to the empty string have their snapshots removed.
+func renameTranslationSnapshots(replaceMap map[string]string) error {
+	snapshots := loadTranslationSnapshots()
+	for workID := range snapshots {
+		snapshots[workID] = changeKeys(snapshots[workID], replaceMap)
+	}
+	return snapshots.save()
+}
+
+// blockHashes hashes the translatable content of each block.
+func blockHashes(blocks []ortfodb.ContentBlock) []string {
+	hashes := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		var content string
+		switch {
+		case block.Type.IsParagraph():
+			content = string(block.Content)
+		case block.Type.IsMedia():
+			content = string(block.RelativeSource) + "\x00" + block.Alt + "\x00" + block.Caption
+		case block.Type.IsLink():
+			content = block.URL + "\x00" + string(block.Text) + "\x00" + block.Link.Title
+		}
+		hash := md5.Sum([]byte(string(block.Type) + "\x00" + content))
+		hashes = append(hashes, base64.StdEncoding.EncodeToString(hash[:]))
+	}
+	return hashes
+}
+
+func sameHashes(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// paragraphIDs returns the IDs of the paragraphs among blocks.
+func paragraphIDs(blocks []ortfodb.ContentBlock) []string {
+	ids := make([]string, 0)
+	for _, block := range blocks {
+		if block.Type.IsParagraph() {
+			ids = append(ids, block.ID)
+		}
+	}
+	return ids
+}
+
+// recordTranslations updates the translation snapshots of workID when it is saved, previous being the work before the save.
+// For every translation that changed, the reference language's paragraphs are recorded as they were before the save,
+// so that reference paragraphs modified at the same time as the translation are still reported as outdated.
+func (settings *Settings) recordTranslations(workID string, previous ortfodb.Work, updated ortfodb.Work) error {
+	if len(settings.PortfolioLanguages) == 0 {
+		return nil
+	}
+	referenceLanguage := settings.PortfolioLanguages[0]
+	reference, ok := previous.Content[referenceLanguage]
+	if !ok {
+		reference = updated.Content[referenceLanguage]
+	}
+
+	snapshots := loadTranslationSnapshots()
+	changed := false
+	for lang, content := range updated.Content {
+		if lang == referenceLanguage {
+			continue
+		}
+		if before, ok := previous.Content[lang]; ok && sameHashes(blockHashes(before.Blocks), blockHashes(content.Blocks)) {
+			continue
+		}
+		if _, ok := snapshots[workID]; !ok {
+			snapshots[workID] = make(map[string]translationSnapshot)
+		}
+		snapshots[workID][lang] = translationSnapshot{ReferenceParagraphs: paragraphIDs(reference.Blocks)}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return snapshots.save()
+}
+
+// TranslationStatus returns the status of every work's translations, by work ID then by language.
+// Paragraphs of the reference language are matched by ID with the ones recorded when the translation was last saved (see recordTranslations).
+// Translations that were never saved from the app have nothing to compare to, and are not reported as outdated.
+func (settings *Settings) TranslationStatus() (map[string]map[string]TranslationStatus, error) {
+	if len(settings.PortfolioLanguages) == 0 {
+		return nil, fmt.Errorf("the portfolio has no languages")
+	}
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("while loading database: %w", err)
+	}
+
+	referenceLanguage := settings.PortfolioLanguages[0]
+	snapshots := loadTranslationSnapshots()
+	statuses := make(map[string]map[string]TranslationStatus)
+	for workID, work := range db {
+		statuses[workID] = make(map[string]TranslationStatus)
+		reference := work.Content[referenceLanguage]
+		for _, lang := range settings.PortfolioLanguages {
+			content, exists := work.Content[lang]
+			status := TranslationStatus{
+				Exists:          exists,
+				Blocks:          len(content.Blocks),
+				ReferenceBlocks: len(reference.Blocks),
+				Outdated:        make([]string, 0),
+			}
+			snapshot, known := snapshots[workID][lang]
+			if exists && lang != referenceLanguage && known && snapshot.ReferenceParagraphs != nil {
+				for _, id := range paragraphIDs(reference.Blocks) {
+					if !containsString(snapshot.ReferenceParagraphs, id) {
+						status.Outdated = append(status.Outdated, id)
+					}
+				}
+			}
+			statuses[workID][lang] = status
+		}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TaxonomyUsage describes which works use a tag or technology.
+// Index is the position of the tag or technology in tags.yaml or technologies.yaml.
+type TaxonomyUsage struct {
+	Index   int      `json:"index"`
+	Name    string   `json:"name"`
+	Count   int      `json:"count"`
+	WorkIDs []string `json:"workIDs"`
+}
+
+// TaxonomyStats describes how tags and technologies are used by works.
+// Undefined* map values used by works that are not declared in tags.yaml or technologies.yaml to the works that use them.
+type TaxonomyStats struct {
+	Tags                  []TaxonomyUsage     `json:"tags"`
+	Technologies          []TaxonomyUsage     `json:"technologies"`
+	UnusedTags            []string            `json:"unusedTags"`
+	UnusedTechnologies    []string            `json:"unusedTechnologies"`
+	UndefinedTags         map[string][]string `json:"undefinedTags"`
+	UndefinedTechnologies map[string][]string `json:"undefinedTechnologies"`
+}
+
+// displayName returns a name for the tag that does not depend on the language.
+func (t Tag) displayName() string {
+	if !t.Singular.IsLocalized() {
+		return t.Singular.Plain
+	}
+	if name := t.Singular.In("default"); name != "" {
+		return name
+	}
+	values := t.Singular.Values()
+	sort.Strings(values)
+	return strings.Join(values, " / ")
+}
+
+// countUsage resolves the values used by each work with resolve, which returns the index of the declared entry a value refers to, or -1.
+// usages must have one entry per declared tag or technology.
+func countUsage(valuesByWork map[string][]string, usages []TaxonomyUsage, resolve func(value string) int) map[string][]string {
+	undefined := make(map[string][]string)
+	workIDs := make([]string, 0, len(valuesByWork))
+	for workID := range valuesByWork {
+		workIDs = append(workIDs, workID)
+	}
+	sort.Strings(workIDs)
+
+	for _, workID := range workIDs {
+		for _, value := range valuesByWork[workID] {
+			index := resolve(value)
+			if index == -1 {
+				if !containsString(undefined[value], workID) {
+					undefined[value] = append(undefined[value], workID)
+				}
+				continue
+			}
+			// A work can refer to the same tag twice, with different aliases
+			if !containsString(usages[index].WorkIDs, workID) {
+				usages[index].WorkIDs = append(usages[index].WorkIDs, workID)
+				usages[index].Count++
+			}
+		}
+	}
+	return undefined
+}
+
+// TaxonomyStats counts the works using each tag and technology, resolving aliases,
+// and finds tags and technologies that are declared but unused, or used but not declared.
+func (settings *Settings) TaxonomyStats() (TaxonomyStats, error) {
+	tags, err := LoadTags()
+	if err != nil {
+		return TaxonomyStats{}, err
+	}
+	technologies, err := LoadTechnologies()
+	if err != nil {
+		return TaxonomyStats{}, err
+	}
+	db, err := settings.LoadDatabase()
+	if err != nil {
+		return TaxonomyStats{}, fmt.Errorf("while loading database: %w", err)
+	}
+
+	stats := TaxonomyStats{
+		Tags:               make([]TaxonomyUsage, len(tags)),
+		Technologies:       make([]TaxonomyUsage, len(technologies)),
+		UnusedTags:         make([]string, 0),
+		UnusedTechnologies: make([]string, 0),
+	}
+	tagsByWork := make(map[string][]string)
+	technologiesByWork := make(map[string][]string)
+	for workID, work := range db {
+		tagsByWork[workID] = work.Metadata.Tags
+		technologiesByWork[workID] = work.Metadata.MadeWith
+	}
+
+	for i, tag := range tags {
+		stats.Tags[i] = TaxonomyUsage{Index: i, Name: tag.displayName(), WorkIDs: make([]string, 0)}
+	}
+	stats.UndefinedTags = countUsage(tagsByWork, stats.Tags, func(value string) int {
+		for i, tag := range tags {
+			if tag.ReferredToBy(value) {
+				return i
+			}
+		}
+		return -1
+	})
+
+	for i, technology := range technologies {
+		stats.Technologies[i] = TaxonomyUsage{Index: i, Name: technology.Slug, WorkIDs: make([]string, 0)}
+	}
+	stats.UndefinedTechnologies = countUsage(technologiesByWork, stats.Technologies, func(value string) int {
+		for i, technology := range technologies {
+			if technology.ReferredToBy(value) {
+				return i
+			}
+		}
+		return -1
+	})
+
+	for _, usage := range stats.Tags {
+		if usage.Count == 0 {
+			stats.UnusedTags = append(stats.UnusedTags, usage.Name)
+		}
+	}
+	for _, usage := range stats.Technologies {
+		if usage.Count == 0 {
+			stats.UnusedTechnologies = append(stats.UnusedTechnologies, usage.Name)
+		}
+	}
+	return stats, nil
+}
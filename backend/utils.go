@@ -15,10 +15,19 @@ func prepareQuotedString(message string, a ...interface{}) string {
 	return strings.ReplaceAll(fmt.Sprintf(message, a...), "`", "\\`")
 }
 
+// LogToBrowser and ErrorToBrowser write to the standard error when there is no webview, as with command-line subcommands.
 func LogToBrowser(message string, a ...interface{}) {
+	if w == nil {
+		fmt.Fprintf(os.Stderr, "[backend] "+message+"\n", a...)
+		return
+	}
 	w.Eval("console.info(`[backend] " + prepareQuotedString(message, a...) + "`)")
 }
 func ErrorToBrowser(message string, a ...interface{}) {
+	if w == nil {
+		fmt.Fprintf(os.Stderr, "[backend] error: "+message+"\n", a...)
+		return
+	}
 	w.Eval("console.error(`[backend] " + prepareQuotedString(message, a...) + "`)")
 }
 
@@ -73,6 +82,16 @@ func changeKeys[K string, V any](m map[K]V, replaceMap map[K]K) map[K]V {
 	return m
 }
 
+// stringsLooselyMatch returns true if s is equal to any of the needles, ignoring case.
+func stringsLooselyMatch(s string, needles ...string) bool {
+	for _, needle := range needles {
+		if strings.EqualFold(s, needle) {
+			return true
+		}
+	}
+	return false
+}
+
 func LogExpression[T any](expression T) T {
 	fmt.Printf("[[[LOG EXPR]]] %#v", expression)
 	return expression
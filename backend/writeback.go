@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	ortfodb "github.com/ortfo/db"
 )
@@ -26,3 +27,49 @@ func Writeback(settings Settings, parsedDescription ortfodb.Work, workID string)
 	LogToBrowser("Writing description to %s", writeTo)
 	return os.WriteFile(writeTo, []byte(description), 0644)
 }
+
+// DescriptionsChangedError is returned when works cannot be written back because their description.md changed since they were built,
+// for example because it was edited outside of ortfo/gui. Writing them back would lose these changes.
+type DescriptionsChangedError struct {
+	WorkIDs []string `json:"workIDs"`
+}
+
+func (e DescriptionsChangedError) Error() string {
+	return fmt.Sprintf("the description of %s changed since the database was built, rebuild it first so that these changes are not lost", strings.Join(e.WorkIDs, ", "))
+}
+
+// descriptionChanged returns true if the description.md of the work with ID workID does not match work's DescriptionHash,
+// in the same way DatabaseStaleness finds outdated works. A work with no description.md yet has nothing to lose.
+func (settings *Settings) descriptionChanged(work ortfodb.Work, workID string) (bool, error) {
+	hash, err := hashFile(JoinPaths(settings.ProjectsFolder, workID, ".ortfo", "description.md"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("while hashing description of %s: %w", workID, err)
+	}
+	return hash != work.DescriptionHash, nil
+}
+
+// writebackWork writes the description of work back, and updates its DescriptionHash to match,
+// so that the work is not seen as outdated when the database is written with it.
+// It refuses to overwrite a description.md that changed since work was built, with a DescriptionsChangedError.
+func (settings *Settings) writebackWork(work *ortfodb.Work, workID string) error {
+	changed, err := settings.descriptionChanged(*work, workID)
+	if err != nil {
+		return err
+	}
+	if changed {
+		return DescriptionsChangedError{WorkIDs: []string{workID}}
+	}
+	err = Writeback(*settings, *work, workID)
+	if err != nil {
+		return err
+	}
+	hash, err := hashFile(JoinPaths(settings.ProjectsFolder, workID, ".ortfo", "description.md"))
+	if err != nil {
+		return fmt.Errorf("while hashing description: %w", err)
+	}
+	work.DescriptionHash = hash
+	return nil
+}